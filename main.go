@@ -15,7 +15,8 @@ var configPath = flag.String("config", ".config.json", "Path to config file (jso
 func main() {
 	flag.Parse()
 
-	cfg := &mover.Config{}
+	// Config files without WhisperSeconds get the same default as environment variables.
+	cfg := &mover.Config{WhisperSeconds: mover.DefaultWhisperSeconds}
 	if *configPath != "" {
 		contents, err := ioutil.ReadFile(*configPath)
 		if err != nil {
@@ -35,6 +36,9 @@ func main() {
 	if err := cfg.Validate(); err != nil {
 		log.Fatalf("Incomplete config: %v", err)
 	}
+	if len(cfg.Guilds) > 0 {
+		log.Printf("Loaded per-guild configuration for %d guild(s).", len(cfg.Guilds))
+	}
 
 	m := mover.New(cfg)
 	if err := m.RunForever(); err != nil {
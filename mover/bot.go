@@ -3,10 +3,14 @@ package mover
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -17,7 +21,12 @@ import (
 type Bot struct {
 	cfg      *Config
 	sessions []*discordgo.Session
-	ch       chan (*movementPlan)
+	mover    guildMemberMover
+	plans    *planDispatcher
+	store    GameStore
+	whispers *whisperRegistry
+	timers   *timerRegistry
+	joins    *joinTracker
 }
 
 // New creates a new BotC multi-bot voice channel mover.
@@ -25,22 +34,43 @@ type Bot struct {
 // Actions are load-balanced across all configured bots in an attempt to reduce Discord
 // throttling issues for large games (>10 players).
 func New(cfg *Config) *Bot {
-	return &Bot{cfg: cfg, ch: make(chan (*movementPlan))}
+	b := &Bot{cfg: cfg, store: newMemoryGameStore(), whispers: newWhisperRegistry(), timers: newTimerRegistry(), joins: newJoinTracker()}
+	b.plans = newPlanDispatcher(b.executeMovementPlan)
+	return b
 }
 
 // Button IDs.
 const (
-	buttonNight = "buttonNight"
-	buttonDay   = "buttonDay"
+	buttonNight        = "buttonNight"
+	buttonDay          = "buttonDay"
+	buttonNewGame      = "buttonNewGame"
+	buttonPreviewNight = "buttonPreviewNight"
+	buttonSilence      = "buttonSilence"
+	buttonNextNight    = "buttonNextNight"
 )
 
-// onButtonPressed handles the 2 button presses for day/night phase movements.
+// onButtonPressed handles the button presses for day/night phase movements and new games, as well
+// as the cottage select menu.
 func (b *Bot) onButtonPressed(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if button, game, n, ok := parseNominationButton(i.MessageComponentData().CustomID); ok {
+		return b.onNominationButton(ctx, &discordSessionWrap{s}, i, button, game, n)
+	}
+
 	switch i.MessageComponentData().CustomID {
 	case buttonNight:
 		return b.prepareNightMoves(ctx, &discordSessionWrap{s}, i)
 	case buttonDay:
 		return b.prepareDayMoves(ctx, &discordSessionWrap{s}, i)
+	case buttonNewGame:
+		return b.startGame(ctx, &discordSessionWrap{s}, i)
+	case buttonPreviewNight:
+		return b.previewMoves(ctx, &discordSessionWrap{s}, i, phaseNight)
+	case buttonSilence:
+		return b.silenceTownSquare(ctx, &discordSessionWrap{s}, i)
+	case buttonNextNight:
+		return b.nextInNightOrder(ctx, &discordSessionWrap{s}, i)
+	case selectCottage:
+		return b.visitCottage(ctx, &discordSessionWrap{s}, i)
 	}
 
 	return fmt.Errorf("unknown button pressed: %#v", i.MessageComponentData())
@@ -48,55 +78,569 @@ func (b *Bot) onButtonPressed(ctx context.Context, s *discordgo.Session, i *disc
 
 // Slash command IDs.
 const (
-	slashCommandButtons = "buttons"
+	slashCommandButtons   = "buttons"
+	slashCommandPreview   = "preview"
+	slashCommandGame      = "game"
+	slashCommandWhisper   = "whisper"
+	slashCommandWhispers  = "whispers"
+	slashCommandSetup     = "setup"
+	slashCommandTimer     = "timer"
+	slashCommandNominate  = "nominate"
+	slashCommandKill      = "kill"
+	slashCommandRevive    = "revive"
+	slashCommandStatus    = "status"
+	slashCommandSeats     = "seats"
+	slashCommandTraveller = "traveller"
+	slashCommandGrimoire  = "grimoire"
+	slashCommandSummon    = "summon"
+	slashCommandReturn    = "return"
+)
+
+// commandAccess describes who may use a slash command.
+type commandAccess int
+
+const (
+	accessStoryTeller commandAccess = iota
+	accessPlayer
+	accessAdmin
+)
+
+// commandAccessLevels contains all slash commands that are not reserved for story tellers. All
+// other slash commands are reserved for story tellers.
+var commandAccessLevels = map[string]commandAccess{
+	slashCommandWhisper: accessPlayer,
+	slashCommandSetup:   accessAdmin,
+	slashCommandStatus:  accessPlayer,
+}
+
+// buttonAccessLevels contains all buttons that are not reserved for story tellers, keyed by the
+// custom ID up to the first colon. All other buttons are reserved for story tellers.
+var buttonAccessLevels = map[string]commandAccess{
+	buttonVote: accessPlayer,
+}
+
+// slashCommands contains all application commands registered by the bot.
+var slashCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:        slashCommandButtons,
+		Description: "Show day/night action buttons.",
+	},
+	{
+		Name:        slashCommandPreview,
+		Description: "Preview the moves for a phase without moving anyone.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "phase",
+				Description: "Phase to preview.",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: phaseNight, Value: phaseNight},
+					{Name: phaseDay, Value: phaseDay},
+				},
+			},
+		},
+	},
+	{
+		Name:        slashCommandGame,
+		Description: "Manage the game.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        gameCommandStart,
+				Description: "Start a new game with everyone in Town Square as seated players.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        gameCommandEnd,
+				Description: "End the game and forget all of its state.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        gameCommandStatus,
+				Description: "Show the phase, day number and seated players.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        gameCommandCleanup,
+				Description: "Delete all cottages that were created by the bot.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        gameCommandScript,
+				Description: "Load a script JSON from the Script Tool or clocktower.online.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        "script",
+						Description: "Script JSON, an array of character IDs.",
+						Required:    true,
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        slashCommandWhisper,
+		Description: "Whisper privately with other players in Town Square.",
+		Options:     whisperPlayerOptionsList(),
+	},
+	{
+		Name:        slashCommandWhispers,
+		Description: "Manage the active whispers.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        whispersCommandList,
+				Description: "Show all active whispers.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        whispersCommandEnd,
+				Description: "End a whisper and return its players to Town Square.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "Voice channel of the whisper. Ends all whispers if omitted.",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        whispersCommandLog,
+				Description: "Show who whispered with whom and for how long.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "day",
+						Description: "Day to show. Defaults to the current day.",
+						MinValue:    new(float64),
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:                     slashCommandSetup,
+		Description:              "Create all missing channels and roles required by the bot.",
+		DefaultMemberPermissions: &adminPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "cottages",
+				Description: fmt.Sprintf("Number of cottages. Defaults to %d.", defaultSetupCottages),
+				MinValue:    &minSetupCottages,
+				MaxValue:    maxSetupCottages,
+			},
+		},
+	},
+	{
+		Name:        slashCommandTimer,
+		Description: "Start a countdown in this channel.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "duration",
+				Description: "Duration in minutes or e.g. 90s or 2m30s, 0 stops the running timer.",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "label",
+				Description: "What the timer is for, e.g. Nominations.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "night",
+				Description: "Send everyone to their cottages when the time is up.",
+			},
+		},
+	},
+	{
+		Name:        slashCommandNominate,
+		Description: "Nominate a player for execution and start the vote.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "nominator",
+				Description: "Player who nominates.",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "nominee",
+				Description: "Player who is nominated.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        slashCommandKill,
+		Description: "Mark a player as dead.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "player",
+				Description: "Player who died.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        slashCommandRevive,
+		Description: "Mark a dead player as alive again.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "player",
+				Description: "Player who is revived.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        slashCommandStatus,
+		Description: "Show the seated players and who is alive or dead.",
+	},
+	{
+		Name:        slashCommandSeats,
+		Description: "Show or change the clockwise seat order, seat N sleeps in cottage N.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        seatsCommandShow,
+				Description: "Show the seat order.",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        seatsCommandSet,
+				Description: "Set the seat order.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "players",
+						Description: "Mentions of all seated players in clockwise order, e.g. @a @b @c.",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        seatsCommandShuffle,
+				Description: "Shuffle the seat order.",
+			},
+		},
+	},
+	{
+		Name:        slashCommandTraveller,
+		Description: "Seat or remove travellers.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        travellerCommandAdd,
+				Description: "Seat a player as a traveller.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "Player who joins the game.",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "seat",
+						Description: "Seat of the traveller. Defaults to the last seat.",
+						MinValue:    &minTravellerSeat,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        travellerCommandRemove,
+				Description: "Remove a traveller who leaves the game.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "Traveller who leaves the game.",
+						Required:    true,
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        slashCommandGrimoire,
+		Description: "Record the characters of the players. Only visible to story tellers.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        grimoireCommandAssign,
+				Description: "Assign a character to a player.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "Player who gets the character.",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "character",
+						Description: "Character, e.g. Fortune Teller.",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "alignment",
+						Description: "Alignment of the player. Defaults to the alignment of the character.",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: alignmentGood, Value: alignmentGood},
+							{Name: alignmentEvil, Value: alignmentEvil},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        grimoireCommandReminder,
+				Description: "Add a reminder token to a player, or remove it if they have it already.",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "player",
+						Description: "Player who gets the reminder.",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "reminder",
+						Description: "Reminder token, e.g. Poisoned.",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        grimoireCommandShow,
+				Description: "Show the grimoire and the night order.",
+			},
+		},
+	},
+	{
+		Name:        slashCommandSummon,
+		Description: "Move a player into your voice channel.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "player",
+				Description: "Player to summon.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        slashCommandReturn,
+		Description: "Move a player back to their cottage at night, or to Town Square during the day.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "player",
+				Description: "Player to return.",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name: userCommandKill,
+		Type: discordgo.UserApplicationCommand,
+	},
+	{
+		Name: userCommandRevive,
+		Type: discordgo.UserApplicationCommand,
+	},
+	{
+		Name: userCommandSummon,
+		Type: discordgo.UserApplicationCommand,
+	},
+	{
+		Name: userCommandReturn,
+		Type: discordgo.UserApplicationCommand,
+	},
+}
+
+// Bounds of the number of cottages created by /setup. A category holds at most 50 channels.
+var (
+	minSetupCottages float64 = 1
+	maxSetupCottages float64 = 50
 )
 
-// onSlashCommand handles the /buttons slash command and responds with the 2 button embeds.
+// minTravellerSeat is the first seat a traveller can take.
+var minTravellerSeat float64 = 1
+
+// whisperPlayerOptionsList returns the player options of the /whisper slash command. The first
+// player is required.
+func whisperPlayerOptionsList() []*discordgo.ApplicationCommandOption {
+	var options []*discordgo.ApplicationCommandOption
+	for n := 1; n <= whisperPlayerOptions; n++ {
+		options = append(options, &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionUser,
+			Name:        fmt.Sprintf("player%d", n),
+			Description: "Player to whisper with.",
+			Required:    n == 1,
+		})
+	}
+	return options
+}
+
+// onSlashCommand handles all slash commands.
 func (b *Bot) onSlashCommand(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
 	data := i.ApplicationCommandData()
-	if data.Name != slashCommandButtons {
-		return fmt.Errorf("unknown slash command: %s", data.Name)
+	switch data.Name {
+	case slashCommandButtons:
+		return b.showButtons(ctx, s, i)
+	case slashCommandPreview:
+		return b.previewMoves(ctx, &discordSessionWrap{s}, i, data.Options[0].StringValue())
+	case slashCommandGame:
+		return b.onGameCommand(ctx, &discordSessionWrap{s}, i)
+	case slashCommandWhisper:
+		return b.startWhisper(ctx, &discordSessionWrap{s}, i)
+	case slashCommandWhispers:
+		return b.onWhispersCommand(ctx, &discordSessionWrap{s}, i)
+	case slashCommandSetup:
+		return b.setup(ctx, &discordSessionWrap{s}, i)
+	case slashCommandTimer:
+		return b.startTimer(ctx, &discordSessionWrap{s}, i)
+	case slashCommandNominate:
+		return b.nominate(ctx, &discordSessionWrap{s}, i)
+	case slashCommandKill, userCommandKill:
+		return b.setAlive(ctx, &discordSessionWrap{s}, i, false)
+	case slashCommandRevive, userCommandRevive:
+		return b.setAlive(ctx, &discordSessionWrap{s}, i, true)
+	case slashCommandStatus:
+		return b.showGameStatus(ctx, &discordSessionWrap{s}, i)
+	case slashCommandSeats:
+		return b.onSeatsCommand(ctx, &discordSessionWrap{s}, i)
+	case slashCommandTraveller:
+		return b.onTravellerCommand(ctx, &discordSessionWrap{s}, i)
+	case slashCommandGrimoire:
+		return b.onGrimoireCommand(ctx, &discordSessionWrap{s}, i)
+	case slashCommandSummon, userCommandSummon:
+		return b.summon(ctx, &discordSessionWrap{s}, i)
+	case slashCommandReturn, userCommandReturn:
+		return b.returnPlayer(ctx, &discordSessionWrap{s}, i)
+	}
+
+	return fmt.Errorf("unknown slash command: %s", data.Name)
+}
+
+// showButtons responds with the button embeds and the cottage select menu.
+func (b *Bot) showButtons(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	vs, err := b.buildDiscordVoiceState(ctx, &discordSessionWrap{s}, i.GuildID)
+	if err != nil {
+		log.Printf("Cannot build voice state for the cottage menu of guild %s: %v", i.GuildID, err)
 	}
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
+			Components: buttonComponents(cottageMenu(vs, nil)),
+		},
+	}, discordgo.WithContext(ctx))
+
+	return nil
+}
+
+// buttonComponents returns the components of the /buttons message.
+func buttonComponents(menu discordgo.SelectMenu) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
-				discordgo.ActionsRow{
-					Components: []discordgo.MessageComponent{
-						discordgo.Button{
-							Emoji:    &discordgo.ComponentEmoji{Name: "☀️"},
-							Label:    "Day: Return to Town Square",
-							CustomID: buttonDay,
-							Style:    discordgo.PrimaryButton,
-						},
-					},
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "☀️"},
+					Label:    "Day: Return to Town Square",
+					CustomID: buttonDay,
+					Style:    discordgo.PrimaryButton,
 				},
-				discordgo.ActionsRow{
-					Components: []discordgo.MessageComponent{
-						discordgo.Button{
-							Emoji:    &discordgo.ComponentEmoji{Name: "🌑"},
-							Label:    "Night: Send all to Cottages",
-							CustomID: buttonNight,
-							Style:    discordgo.DangerButton,
-						},
-					},
+			},
+		},
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "🌑"},
+					Label:    "Night: Send all to Cottages",
+					CustomID: buttonNight,
+					Style:    discordgo.DangerButton,
+				},
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "⏭️"},
+					Label:    "Next in Night Order",
+					CustomID: buttonNextNight,
+					Style:    discordgo.SecondaryButton,
 				},
 			},
 		},
-	}, discordgo.WithContext(ctx))
-
-	return nil
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "🔄"},
+					Label:    "New Game: Seat Town Square",
+					CustomID: buttonNewGame,
+					Style:    discordgo.SecondaryButton,
+				},
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "🔍"},
+					Label:    "Preview Night",
+					CustomID: buttonPreviewNight,
+					Style:    discordgo.SecondaryButton,
+				},
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "🔇"},
+					Label:    "Silence Town Square",
+					CustomID: buttonSilence,
+					Style:    discordgo.SecondaryButton,
+				},
+			},
+		},
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{menu},
+		},
+	}
 }
 
 // discordVoiceState contains all required discord guild and voice state information to perform
 // day or night moves.
 type discordVoiceState struct {
-	guild            *discordgo.Guild
-	userToVoiceState map[string]*discordgo.VoiceState
-	members          []*discordgo.Member
-	townSquare       *discordgo.Channel
-	cottages         []*discordgo.Channel
+	guild             *discordgo.Guild
+	userToVoiceState  map[string]*discordgo.VoiceState
+	members           []*discordgo.Member
+	channels          map[string]*discordgo.Channel
+	dayCategory       *discordgo.Channel
+	townSquare        *discordgo.Channel
+	cottages          []*discordgo.Channel
+	storyTellerRoleID string
+	// spectatorRoleID is empty if no spectator role is configured.
+	spectatorRoleID string
+	// ignoredChannelIDs contains all channels whose members are never moved into cottages.
+	ignoredChannelIDs map[string]bool
+}
+
+// isStoryTeller returns true iff the member has the story teller role.
+func (vs *discordVoiceState) isStoryTeller(member *discordgo.Member) bool {
+	return slices.Contains(member.Roles, vs.storyTellerRoleID)
+}
+
+// inDayCategory returns true iff the channel belongs to the day category, like Town Square.
+func (vs *discordVoiceState) inDayCategory(channelID string) bool {
+	channel := vs.channels[channelID]
+	return channel != nil && channel.ParentID == vs.dayCategory.ID
+}
+
+// isSpectator returns true iff the member has the spectator role.
+func (vs *discordVoiceState) isSpectator(member *discordgo.Member) bool {
+	return vs.spectatorRoleID != "" && slices.Contains(member.Roles, vs.spectatorRoleID)
 }
 
 // discordSessionWrap wraps a discordgo session to simplify unit testing.
@@ -111,13 +655,40 @@ func (s *discordSessionWrap) StateGuild(guildID string) (*discordgo.Guild, error
 	return s.State.Guild(guildID)
 }
 
+// Download downloads an attachment. Attachments are served by discord's CDN rather than the API,
+// so there is no session method for it. Reads at most maxScriptSize bytes.
+func (s *discordSessionWrap) Download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxScriptSize))
+}
+
 // discordSession interface used by the bot. Can be exchanged for a fake in unit tests.
 type discordSession interface {
 	GuildChannels(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Channel, error)
 	StateGuild(guildID string) (*discordgo.Guild, error)
 	GuildMembers(guildID string, after string, limit int, options ...discordgo.RequestOption) ([]*discordgo.Member, error)
 	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
 	GuildRoles(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Role, error)
+	GuildChannelCreateComplex(guildID string, data discordgo.GuildChannelCreateData, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	ChannelDelete(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	GuildRoleCreate(guildID string, data *discordgo.RoleParams, options ...discordgo.RequestOption) (*discordgo.Role, error)
+	GuildMember(guildID string, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error)
+	GuildMemberNickname(guildID string, userID string, nickname string, options ...discordgo.RequestOption) error
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	Download(ctx context.Context, url string) ([]byte, error)
 }
 
 // buildDiscordVoiceState returns information about all mandatory voice channels and members in
@@ -128,43 +699,29 @@ func (b *Bot) buildDiscordVoiceState(ctx context.Context, s discordSession, guil
 		return nil, fmt.Errorf("cannot list guild channels: %w", err)
 	}
 
-	var dayCategoryChannel, nightCategoryChannel, townSquareChannel *discordgo.Channel
-	for _, channel := range channels {
-		switch channel.Name {
-		case b.cfg.DayPhaseCategory:
-			dayCategoryChannel = channel
-		case b.cfg.NightPhaseCategory:
-			nightCategoryChannel = channel
-		case b.cfg.TownSquare:
-			townSquareChannel = channel
-		}
-	}
-
+	cfg := b.cfg.ForGuild(guildID)
+	dayCategoryChannel := findChannel(channels, cfg.DayPhaseCategoryID, cfg.DayPhaseCategory)
 	if dayCategoryChannel == nil {
-		return nil, fmt.Errorf("cannot find day category %q", b.cfg.DayPhaseCategory)
+		return nil, fmt.Errorf("cannot find day category %s", configRef(cfg.DayPhaseCategoryID, cfg.DayPhaseCategory))
 	}
+	nightCategoryChannel := findChannel(channels, cfg.NightPhaseCategoryID, cfg.NightPhaseCategory)
 	if nightCategoryChannel == nil {
-		return nil, fmt.Errorf("cannot find night category %q", b.cfg.NightPhaseCategory)
+		return nil, fmt.Errorf("cannot find night category %s", configRef(cfg.NightPhaseCategoryID, cfg.NightPhaseCategory))
 	}
+	townSquareChannel := findChannel(channels, cfg.TownSquareID, cfg.TownSquare)
 	if townSquareChannel == nil {
-		return nil, fmt.Errorf("cannot find Town Square %q", b.cfg.TownSquare)
+		return nil, fmt.Errorf("cannot find Town Square %s", configRef(cfg.TownSquareID, cfg.TownSquare))
 	}
 	if townSquareChannel.ParentID != dayCategoryChannel.ID {
 		return nil, fmt.Errorf("town square is not under day phase")
 	}
 
-	var cottages []*discordgo.Channel
-	for _, channel := range channels {
-		if channel.Type == discordgo.ChannelTypeGuildVoice && channel.ParentID == nightCategoryChannel.ID {
-			cottages = append(cottages, channel)
-		}
+	// Use the top N cottages according to their position.
+	cottages := cottagesOf(channels, nightCategoryChannel)
+	if cfg.MaxCottages > 0 && len(cottages) > cfg.MaxCottages {
+		cottages = cottages[:cfg.MaxCottages]
 	}
 
-	// Sort the cottages according to their position and use the top N cottages.
-	slices.SortFunc(cottages, func(a, b *discordgo.Channel) int {
-		return a.Position - b.Position
-	})
-
 	guild, err := s.StateGuild(guildID)
 	if err != nil {
 		return nil, fmt.Errorf("cannot fetch guild state: %w", err)
@@ -177,13 +734,35 @@ func (b *Bot) buildDiscordVoiceState(ctx context.Context, s discordSession, guil
 	if err != nil {
 		return nil, fmt.Errorf("cannot list guild members: %w", err)
 	}
+	storyTellerRoleID, err := b.findStoryTellerRole(ctx, s, guildID)
+	if err != nil {
+		return nil, err
+	}
+	spectatorRoleID, err := b.findSpectatorRole(ctx, s, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	channelsByID := make(map[string]*discordgo.Channel)
+	ignoredChannelIDs := make(map[string]bool)
+	for _, channel := range channels {
+		channelsByID[channel.ID] = channel
+		if slices.Contains(cfg.IgnoredChannels, channel.ID) || slices.Contains(cfg.IgnoredChannels, channel.Name) {
+			ignoredChannelIDs[channel.ID] = true
+		}
+	}
 
 	return &discordVoiceState{
-		guild:            guild,
-		userToVoiceState: userToVoiceState,
-		members:          members,
-		townSquare:       townSquareChannel,
-		cottages:         cottages,
+		guild:             guild,
+		userToVoiceState:  userToVoiceState,
+		members:           members,
+		channels:          channelsByID,
+		dayCategory:       dayCategoryChannel,
+		townSquare:        townSquareChannel,
+		cottages:          cottages,
+		storyTellerRoleID: storyTellerRoleID,
+		spectatorRoleID:   spectatorRoleID,
+		ignoredChannelIDs: ignoredChannelIDs,
 	}, nil
 }
 
@@ -203,13 +782,89 @@ func forwardInteractionError(s *discordgo.Session, i *discordgo.InteractionCreat
 func (b *Bot) prepareNightMoves(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
 	log.Println("Moving to night.")
 
+	plan, vs, err := b.buildNightPlan(ctx, s, i)
+	var cottagesErr *notEnoughCottagesError
+	if errors.As(err, &cottagesErr) && b.cfg.ForGuild(i.GuildID).AutoCreateCottages {
+		// Creating cottages takes longer than discord waits for the interaction to be acknowledged.
+		return b.deferInteraction(ctx, s, i, func(s discordSession) error {
+			if err := b.createCottages(ctx, s, i.GuildID, cottagesErr.missing()); err != nil {
+				return err
+			}
+			plan, vs, err := b.buildNightPlan(ctx, s, i)
+			if err != nil {
+				return err
+			}
+			return b.dispatchNightPlan(ctx, s, i, plan, vs)
+		})
+	}
+	if err != nil {
+		return err
+	}
+	return b.dispatchNightPlan(ctx, s, i, plan, vs)
+}
+
+// dispatchNightPlan dispatches the plan for the night phase and remembers tonight's cottages.
+func (b *Bot) dispatchNightPlan(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, plan *movementPlan, vs *discordVoiceState) error {
+	plan.onDone = func(*movementReport) {
+		b.closeWhispers(s, i.GuildID)
+		b.refreshCottageMenu(s, i, vs, plan.moves)
+	}
+
+	if ok, err := b.dispatchPlan(ctx, s, i, plan); !ok {
+		return err
+	}
+
+	// Remember tonight's cottages, including those of players who already were in a cottage.
+	if err := b.store.Update(i.GuildID, func(state *GameState) error {
+		state.enterPhase(phaseNight)
+		state.Cottages = make(map[string]string)
+		for user, cottageID := range plan.inPlace {
+			state.Cottages[user] = cottageID
+		}
+		for user, cottageID := range plan.moves {
+			state.Cottages[user] = cottageID
+		}
+		return nil
+	}); err != nil {
+		log.Printf("Cannot update game state for guild %s: %v", i.GuildID, err)
+	}
+
+	return nil
+}
+
+// skipAtNight returns true iff the member in the given voice channel must not be moved into a
+// cottage, e.g. because they are a spectator or hang out in an unrelated voice channel. Story
+// tellers are always moved.
+func skipAtNight(cfg *Config, vs *discordVoiceState, member *discordgo.Member, channelID string) bool {
+	if vs.isStoryTeller(member) {
+		return false
+	}
+	if vs.isSpectator(member) || vs.ignoredChannelIDs[channelID] {
+		return true
+	}
+	if cfg.NightMovesFromDayCategoryOnly {
+		channel, ok := vs.channels[channelID]
+		return !ok || channel.ParentID != vs.dayCategory.ID
+	}
+	return false
+}
+
+// buildNightPlan builds the movement plan for the night phase without dispatching it.
+func (b *Bot) buildNightPlan(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) (*movementPlan, *discordVoiceState, error) {
 	vs, err := b.buildDiscordVoiceState(ctx, s, i.GuildID)
 	if err != nil {
-		return fmt.Errorf("cannot build voice state: %w", err)
+		return nil, nil, fmt.Errorf("cannot build voice state: %w", err)
 	}
 
 	log.Printf("Found all relevant channels and %d cottages for the night phase.", len(vs.cottages))
 
+	cfg := b.cfg.ForGuild(i.GuildID)
+
+	state, err := b.store.Get(i.GuildID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot load game state: %w", err)
+	}
+
 	nightCottageChannelIDs := make(map[string]bool)
 	for _, cottage := range vs.cottages {
 		nightCottageChannelIDs[cottage.ID] = true
@@ -218,8 +873,12 @@ func (b *Bot) prepareNightMoves(ctx context.Context, s discordSession, i *discor
 	// Anyone who isn't already in a private night time cottage needs to move.
 	var storyTellerCottageID string
 	fullCottageIDs := make(map[string]bool)
+	inPlace := make(map[string]string)
 	var userNeedsMove []*discordgo.Member
 	for _, member := range vs.members {
+		if !state.isSeated(member.User.ID) && !vs.isStoryTeller(member) {
+			continue // Only seated players and story tellers take part in the game, dead or alive.
+		}
 		userVoiceState := vs.userToVoiceState[member.User.ID]
 		if userVoiceState != nil && userVoiceState.ChannelID != "" {
 			// This member is in a voice channel.
@@ -227,10 +886,11 @@ func (b *Bot) prepareNightMoves(ctx context.Context, s discordSession, i *discor
 			// This only happens if a new player joins during the night phase.
 			if nightCottageChannelIDs[userVoiceState.ChannelID] {
 				fullCottageIDs[userVoiceState.ChannelID] = true
+				inPlace[member.User.ID] = userVoiceState.ChannelID
 				if storyTellerCottageID == "" && member.User.ID == i.Member.User.ID {
 					storyTellerCottageID = userVoiceState.ChannelID
 				}
-			} else {
+			} else if !skipAtNight(cfg, vs, member, userVoiceState.ChannelID) {
 				// Otherwise, they need to move.
 				userNeedsMove = append(userNeedsMove, member)
 			}
@@ -238,34 +898,57 @@ func (b *Bot) prepareNightMoves(ctx context.Context, s discordSession, i *discor
 	}
 
 	if len(userNeedsMove) > len(nightCottageChannelIDs)-len(fullCottageIDs) {
-		return fmt.Errorf("not enough cottages available, need %d user movements but only have %d empty cottages", len(userNeedsMove), len(nightCottageChannelIDs)-len(fullCottageIDs))
+		return nil, nil, &notEnoughCottagesError{needed: len(userNeedsMove), available: len(nightCottageChannelIDs) - len(fullCottageIDs)}
 	}
 
-	// Find the story teller role ID.
-	allRoles, err := s.GuildRoles(i.GuildID, discordgo.WithContext(ctx))
-	if err != nil {
-		return fmt.Errorf("cannot fetch guild roles: %w", err)
+	// Build the movement plan. Seated players sleep in the cottage of their seat, i.e. seat N in
+	// cottage N, if it is still free. Optionally, cottages are taken in night order instead.
+	seats := state.Players
+	if cfg.CottagesInNightOrder {
+		seats = state.cottageOrder()
 	}
-	var storyTellerRoleID string
-	for _, role := range allRoles {
-		if role.Name == b.cfg.StoryTellerRole {
-			storyTellerRoleID = role.ID
-			break
+	plan := make(map[string]string)
+	var unseated []*discordgo.Member
+	for _, member := range userNeedsMove {
+		seat := slices.Index(seats, member.User.ID)
+		if seat < 0 || seat >= len(vs.cottages) || fullCottageIDs[vs.cottages[seat].ID] {
+			unseated = append(unseated, member)
+			continue
 		}
-	}
-	if storyTellerRoleID == "" {
-		return fmt.Errorf("cannot determine story teller role ID for name %s", b.cfg.StoryTellerRole)
+		plan[member.User.ID] = vs.cottages[seat].ID
+		fullCottageIDs[vs.cottages[seat].ID] = true
 	}
 
-	// Build the movement plan.
-	plan := make(map[string]string)
-	for _, member := range userNeedsMove {
-		isStoryTeller := slices.Contains(member.Roles, storyTellerRoleID)
+	// Everyone else first returns to the cottage they used during the previous night if it is
+	// still free.
+	remembered := state.Cottages
+	var newPlayers []*discordgo.Member
+	for _, member := range unseated {
+		isStoryTeller := vs.isStoryTeller(member)
 		if isStoryTeller && storyTellerCottageID != "" {
 			// Move story tellers into the same cottage at night.
 			plan[member.User.ID] = storyTellerCottageID
 			continue
 		}
+		cottageID, ok := remembered[member.User.ID]
+		if !ok || !nightCottageChannelIDs[cottageID] || fullCottageIDs[cottageID] {
+			newPlayers = append(newPlayers, member)
+			continue
+		}
+		plan[member.User.ID] = cottageID
+		fullCottageIDs[cottageID] = true
+		if isStoryTeller {
+			storyTellerCottageID = cottageID
+		}
+	}
+
+	// Everyone else takes the remaining free cottages.
+	for _, member := range newPlayers {
+		isStoryTeller := vs.isStoryTeller(member)
+		if isStoryTeller && storyTellerCottageID != "" {
+			plan[member.User.ID] = storyTellerCottageID
+			continue
+		}
 		for _, cottage := range vs.cottages {
 			if fullCottageIDs[cottage.ID] {
 				continue // This cottage is already full.
@@ -280,59 +963,193 @@ func (b *Bot) prepareNightMoves(ctx context.Context, s discordSession, i *discor
 	}
 
 	if len(plan) != len(userNeedsMove) {
-		return fmt.Errorf("could not find a move for every player, plan %d vs needed moves %d", len(plan), len(userNeedsMove))
+		return nil, nil, fmt.Errorf("could not find a move for every player, plan %d vs needed moves %d", len(plan), len(userNeedsMove))
 	}
 
-	select {
-	case b.ch <- &movementPlan{moves: plan, guild: i.GuildID}:
-		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseDeferredMessageUpdate,
-			Data: &discordgo.InteractionResponseData{
-				Flags: discordgo.MessageFlagsEphemeral,
-			},
-		}, discordgo.WithContext(ctx))
-	default:
-		// NOTE: If we ever want to provide this bot as a service (vs self-hosted), we should allow
-		// concurrent movement plans (for different guild IDs).
-		return fmt.Errorf("existing player movement has not finished yet, please wait")
+	// Cottage occupants are muted or un-muted once everyone is in their cottage. Story tellers are
+	// never muted.
+	var mutes map[string]bool
+	if cfg.CottageMute != "" {
+		mutes = make(map[string]bool)
+		mute := cfg.CottageMute == cottageMuteOn
+		for _, member := range vs.members {
+			_, moved := plan[member.User.ID]
+			_, ok := inPlace[member.User.ID]
+			if !moved && !ok || vs.isStoryTeller(member) {
+				continue
+			}
+			if vs.userToVoiceState[member.User.ID].Mute != mute {
+				mutes[member.User.ID] = mute
+			}
+		}
 	}
+
+	return &movementPlan{moves: plan, inPlace: inPlace, mutes: mutes, guild: i.GuildID}, vs, nil
 }
 
 // prepareDayMoves prepares all necessary moves for the day phase and dispatches the plan.
 func (b *Bot) prepareDayMoves(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
 	log.Println("Moving to day.")
 
+	plan, vs, err := b.buildDayPlan(ctx, s, i)
+	if err != nil {
+		return err
+	}
+	plan.onDone = func(*movementReport) {
+		b.closeWhispers(s, i.GuildID)
+		b.refreshCottageMenu(s, i, vs, plan.moves)
+	}
+
+	if ok, err := b.dispatchPlan(ctx, s, i, plan); !ok {
+		return err
+	}
+
+	if err := b.store.Update(i.GuildID, func(state *GameState) error {
+		state.enterPhase(phaseDay)
+		return nil
+	}); err != nil {
+		log.Printf("Cannot update game state for guild %s: %v", i.GuildID, err)
+	}
+
+	return nil
+}
+
+// buildDayPlan builds the movement plan for the day phase without dispatching it.
+func (b *Bot) buildDayPlan(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) (*movementPlan, *discordVoiceState, error) {
 	vs, err := b.buildDiscordVoiceState(ctx, s, i.GuildID)
 	if err != nil {
-		return fmt.Errorf("cannot build voice state: %w", err)
+		return nil, nil, fmt.Errorf("cannot build voice state: %w", err)
 	}
 
 	log.Printf("Found all relevant channels for the day phase.")
 
-	// Anyone who isn't already in Town Square needs to move.
+	state, err := b.store.Get(i.GuildID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot load game state: %w", err)
+	}
+
+	// Anyone who isn't already in Town Square needs to move. Everyone is un-muted at day.
 	plan := make(map[string]string)
+	inPlace := make(map[string]string)
+	mutes := make(map[string]bool)
 	for _, member := range vs.members {
 		userVoiceState := vs.userToVoiceState[member.User.ID]
-		if userVoiceState != nil && userVoiceState.ChannelID != "" {
-			// This member is in a voice channel.
-			// If they are not in Town Square, they need to move.
-			if userVoiceState.ChannelID != vs.townSquare.ID {
-				plan[member.User.ID] = vs.townSquare.ID
+		if userVoiceState == nil || userVoiceState.ChannelID == "" {
+			continue // This member is not in a voice channel.
+		}
+		if !state.isSeated(member.User.ID) && !vs.isStoryTeller(member) {
+			// Only seated players and story tellers take part in the game, but anyone in the day
+			// category may have been silenced in Town Square.
+			if userVoiceState.Mute && vs.inDayCategory(userVoiceState.ChannelID) {
+				mutes[member.User.ID] = false
 			}
+			continue
+		}
+		// If they are not in Town Square, they need to move.
+		if userVoiceState.ChannelID != vs.townSquare.ID {
+			plan[member.User.ID] = vs.townSquare.ID
+		} else {
+			inPlace[member.User.ID] = vs.townSquare.ID
+		}
+		if userVoiceState.Mute {
+			mutes[member.User.ID] = false
 		}
 	}
 
-	select {
-	case b.ch <- &movementPlan{moves: plan, guild: i.GuildID}:
-		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseDeferredMessageUpdate,
-			Data: &discordgo.InteractionResponseData{
-				Flags: discordgo.MessageFlagsEphemeral,
-			},
-		}, discordgo.WithContext(ctx))
-	default:
-		return fmt.Errorf("existing player movement has not finished yet, please wait")
+	return &movementPlan{moves: plan, inPlace: inPlace, mutes: mutes, guild: i.GuildID}, vs, nil
+}
+
+// silenceTownSquare server-mutes everyone in Town Square except story tellers, e.g. while the
+// story teller explains the rules. Everyone is un-muted again at day.
+func (b *Bot) silenceTownSquare(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	vs, err := b.buildDiscordVoiceState(ctx, s, i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot build voice state: %w", err)
+	}
+
+	mutes := make(map[string]bool)
+	for _, member := range vs.members {
+		userVoiceState := vs.userToVoiceState[member.User.ID]
+		if userVoiceState == nil || userVoiceState.ChannelID != vs.townSquare.ID || userVoiceState.Mute || vs.isStoryTeller(member) {
+			continue
+		}
+		mutes[member.User.ID] = true
+	}
+	if len(mutes) == 0 {
+		return respondEphemeral(ctx, s, i, "Everyone in Town Square is already muted.")
 	}
+
+	log.Printf("Silencing %d user(s) in Town Square.", len(mutes))
+	_, err = b.dispatchPlan(ctx, s, i, &movementPlan{mutes: mutes, guild: i.GuildID})
+	return err
+}
+
+// deferredSession is a discord session for interactions that have been acknowledged with a
+// deferred response already. Responses edit the deferred response instead.
+type deferredSession struct {
+	discordSession
+}
+
+func (d deferredSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	if resp.Data == nil || resp.Data.Content == "" {
+		return nil
+	}
+	_, err := d.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{
+		Content:         &resp.Data.Content,
+		AllowedMentions: resp.Data.AllowedMentions,
+	}, options...)
+	return err
+}
+
+// deferInteraction acknowledges the interaction with a deferred ephemeral response before running
+// f, for actions that may take longer than discord waits for the acknowledgement. Responses of f
+// and the error it returns, if any, edit the deferred response.
+func (b *Bot) deferInteraction(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, f func(s discordSession) error) error {
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}, discordgo.WithContext(ctx)); err != nil {
+		return fmt.Errorf("cannot acknowledge interaction: %w", err)
+	}
+
+	if err := f(deferredSession{s}); err != nil {
+		log.Printf("Interaction error: %v", err)
+		content := fmt.Sprintf("Interaction error: %v", err)
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}, discordgo.WithContext(ctx)); err != nil {
+			log.Printf("Cannot edit interaction response: %v", err)
+		}
+	}
+	return nil
+}
+
+// dispatchPlan acknowledges the interaction with a deferred ephemeral response and dispatches the
+// plan. The response is edited with the movement report once the plan has been executed. Returns
+// false if the plan was not dispatched, e.g. because another plan for the guild is still running.
+func (b *Bot) dispatchPlan(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, plan *movementPlan) (bool, error) {
+	// The interaction has to be acknowledged before dispatching, otherwise a quick plan could try to
+	// edit a response that does not exist yet.
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}, discordgo.WithContext(ctx)); err != nil {
+		return false, fmt.Errorf("cannot acknowledge interaction: %w", err)
+	}
+
+	plan.interaction = i.Interaction
+	plan.session = s
+	if !b.plans.Dispatch(plan) {
+		content := "Existing player movement has not finished yet, please wait."
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}, discordgo.WithContext(ctx)); err != nil {
+			log.Printf("Cannot edit interaction response: %v", err)
+		}
+		return false, nil
+	}
+
+	return true, nil
 }
 
 // checkUserIsStoryTeller returns an error iff the interaction user is not a story teller or if the
@@ -342,22 +1159,9 @@ func (b *Bot) checkUserIsStoryTeller(ctx context.Context, s discordSession, guil
 		return fmt.Errorf("action not invoked from guild channel")
 	}
 
-	// Fetch all guild roles.
-	allRoles, err := s.GuildRoles(guildID, discordgo.WithContext(ctx))
+	storyTellerRoleID, err := b.findStoryTellerRole(ctx, s, guildID)
 	if err != nil {
-		return fmt.Errorf("cannot fetch guild roles: %w", err)
-	}
-
-	var storyTellerRoleID string
-	for _, role := range allRoles {
-		if role.Name == b.cfg.StoryTellerRole {
-			storyTellerRoleID = role.ID
-			break
-		}
-	}
-
-	if storyTellerRoleID == "" {
-		return fmt.Errorf("cannot find story teller role %s among %#v", b.cfg.StoryTellerRole, allRoles)
+		return err
 	}
 
 	if slices.Contains(member.Roles, storyTellerRoleID) {
@@ -368,19 +1172,71 @@ func (b *Bot) checkUserIsStoryTeller(ctx context.Context, s discordSession, guil
 	return fmt.Errorf("user %v (%v) is not a story teller", member.User.Username, member.DisplayName())
 }
 
-// handleMovementPlans listens for and handles new movement plans. Only one plan can be executed
-// at once.
-func (b *Bot) handleMovementPlans() {
-	m := &simpleGuildMemberMover{sessions: b.sessions}
-	for plan := range b.ch {
-		log.Printf("Received new movement plan: %v", plan)
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(b.cfg.MovementDeadlineSeconds))
-		if err := plan.Execute(ctx, b.cfg, m); err != nil {
-			log.Printf("Executing movement plan failed: %v", err)
-		} else {
-			log.Printf("Successfully finished movement plan.")
+// checkAccess returns an error iff the interaction user may not use the interaction. Most
+// interactions are reserved for story tellers, see commandAccessLevels and buttonAccessLevels for
+// all exceptions.
+func (b *Bot) checkAccess(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	if i.Member == nil {
+		return fmt.Errorf("action not invoked from guild channel")
+	}
+
+	access := accessStoryTeller
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		access = commandAccessLevels[i.ApplicationCommandData().Name]
+	case discordgo.InteractionMessageComponent:
+		button, _, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+		access = buttonAccessLevels[button]
+	}
+
+	switch access {
+	case accessPlayer:
+		return nil
+	case accessAdmin:
+		if i.Member.Permissions&discordgo.PermissionAdministrator == 0 {
+			return fmt.Errorf("user %v (%v) is not an administrator", i.Member.User.Username, i.Member.DisplayName())
 		}
-		cancel()
+		return nil
+	}
+	return b.checkUserIsStoryTeller(ctx, s, i.GuildID, i.Member)
+}
+
+// executeMovementPlan executes a single movement plan. Called by the plan dispatcher, which
+// ensures that only one plan per guild is executed at once.
+func (b *Bot) executeMovementPlan(plan *movementPlan) {
+	log.Printf("Received new movement plan: %v", plan)
+	cfg := b.cfg.ForGuild(plan.guild)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(cfg.MovementDeadlineSeconds))
+	defer cancel()
+
+	report := plan.Execute(ctx, cfg, b.mover)
+	if err := report.Err(); err != nil {
+		log.Printf("Executing movement plan for guild %s failed: %v", plan.guild, err)
+	} else {
+		log.Printf("Successfully finished movement plan for guild %s.", plan.guild)
+	}
+
+	if plan.onDone != nil {
+		plan.onDone(report)
+	}
+
+	if plan.interaction == nil {
+		return
+	}
+
+	// The movement deadline may have passed already, the report deserves its own deadline.
+	reportCtx, reportCancel := context.WithTimeout(context.Background(), time.Duration(cfg.PerRequestSeconds)*time.Second)
+	defer reportCancel()
+
+	summary := report.Summary()
+	if plan.description != "" {
+		summary = plan.description + "\n" + summary
+	}
+	if _, err := plan.session.InteractionResponseEdit(plan.interaction, &discordgo.WebhookEdit{
+		Content:         &summary,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}, discordgo.WithContext(reportCtx)); err != nil {
+		log.Printf("Cannot report movement result for guild %s: %v", plan.guild, err)
 	}
 }
 
@@ -412,23 +1268,47 @@ func (b *Bot) RunForever() error {
 	// Only session 1 will listen to commands from users. Other sessions
 	// only act according to session 1.
 
-	// Create the /buttons slash command.
-	if _, err := b.sessions[0].ApplicationCommandCreate(b.sessions[0].State.User.ID, "", &discordgo.ApplicationCommand{
-		Name:        "buttons",
-		Description: "Show day/night action buttons.",
-	}); err != nil {
-		return fmt.Errorf("cannot create application command: %w", err)
+	// Create all slash commands.
+	for _, command := range slashCommands {
+		if _, err := b.sessions[0].ApplicationCommandCreate(b.sessions[0].State.User.ID, "", command); err != nil {
+			return fmt.Errorf("cannot create application command %s: %w", command.Name, err)
+		}
+	}
+
+	// Check the configured channel and role IDs of every guild. Misconfigured guilds are only
+	// reported, the bot keeps serving all other guilds.
+	for _, guild := range b.sessions[0].State.Guilds {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(b.cfg.ForGuild(guild.ID).PerRequestSeconds)*time.Second)
+		if err := b.validateGuildConfig(ctx, &discordSessionWrap{b.sessions[0]}, guild.ID); err != nil {
+			log.Printf("Invalid configuration for guild %s: %v", guild.ID, err)
+		}
+		cancel()
 	}
 
-	defer close(b.ch)
-	go b.handleMovementPlans()
+	// Games survive restarts if a state file is configured.
+	if b.cfg.StateFile != "" {
+		store, err := newFileGameStore(b.cfg.StateFile)
+		if err != nil {
+			return fmt.Errorf("cannot open game state store: %w", err)
+		}
+		b.store = store
+	}
+
+	b.mover = newRateLimitedMover(b.sessions)
+	defer b.plans.Wait()
+
+	// Log conversations in side rooms. The session state already contains the new voice state.
+	b.sessions[0].AddHandler(func(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+		b.trackSideRooms(&discordSessionWrap{s}, v.GuildID, time.Now())
+		b.trackTownSquareJoins(&discordSessionWrap{s}, v.GuildID, time.Now())
+	})
 
 	// Listen for commands.
 	b.sessions[0].AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(b.cfg.PerRequestSeconds)*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(b.cfg.ForGuild(i.GuildID).PerRequestSeconds)*time.Second)
 		defer cancel()
 
-		if err := b.checkUserIsStoryTeller(ctx, &discordSessionWrap{s}, i.GuildID, i.Member); err != nil {
+		if err := b.checkAccess(ctx, &discordSessionWrap{s}, i); err != nil {
 			log.Printf("Invalid user: %v", err)
 			return
 		}
@@ -4,15 +4,32 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/go-cmp/cmp"
+	"golang.org/x/exp/slices"
 )
 
 type fakeDiscordSession struct {
 	id               string
 	userToChannelMap map[string]string
+	responses        []*discordgo.InteractionResponse
+	// voiceStates replaces the default voice states of the guild if set.
+	voiceStates     []*discordgo.VoiceState
+	createdChannels []discordgo.GuildChannelCreateData
+	deletedChannels []string
+	createdRoles    []*discordgo.RoleParams
+	edits           []*discordgo.WebhookEdit
+	messages        []*discordgo.MessageSend
+	messageEdits    []*discordgo.MessageEdit
+	// nicknames maps user IDs to the nicknames set by the bot.
+	nicknames map[string]string
+	// downloads maps URLs to their content.
+	downloads map[string][]byte
+	mu        sync.Mutex
 }
 
 func (f *fakeDiscordSession) GuildChannels(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Channel, error) {
@@ -20,19 +37,34 @@ func (f *fakeDiscordSession) GuildChannels(guildID string, options ...discordgo.
 		return nil, fmt.Errorf("unknown guild: %v", guildID)
 	}
 
-	return []*discordgo.Channel{
+	channels := []*discordgo.Channel{
 		{Name: "day phase", ID: "day phase", ParentID: "root", Type: discordgo.ChannelTypeGuildCategory},
 		{Name: "townsquare", ID: "townsquare", ParentID: "day phase", Type: discordgo.ChannelTypeGuildVoice},
 		{Name: "inn", ID: "inn", ParentID: "day phase", Type: discordgo.ChannelTypeGuildVoice},
 		{Name: "hotel", ID: "hotel", ParentID: "day phase", Type: discordgo.ChannelTypeGuildVoice},
 		{Name: "barber", ID: "barber", ParentID: "day phase", Type: discordgo.ChannelTypeGuildVoice},
 		{Name: "night phase", ID: "night phase", ParentID: "root", Type: discordgo.ChannelTypeGuildCategory},
-		{Name: "cottage1", ID: "cottage1", ParentID: "night phase", Type: discordgo.ChannelTypeGuildVoice},
-		{Name: "cottage2", ID: "cottage2", ParentID: "night phase", Type: discordgo.ChannelTypeGuildVoice},
-		{Name: "cottage3", ID: "cottage3", ParentID: "night phase", Type: discordgo.ChannelTypeGuildVoice},
-		{Name: "cottage4", ID: "cottage4", ParentID: "night phase", Type: discordgo.ChannelTypeGuildVoice},
-		{Name: "cottage5", ID: "cottage5", ParentID: "night phase", Type: discordgo.ChannelTypeGuildVoice},
-	}, nil
+		{Name: "cottage1", ID: "cottage1", ParentID: "night phase", Type: discordgo.ChannelTypeGuildVoice, Position: 1, PermissionOverwrites: privateCottage},
+		{Name: "cottage2", ID: "cottage2", ParentID: "night phase", Type: discordgo.ChannelTypeGuildVoice, Position: 2, PermissionOverwrites: privateCottage},
+		{Name: "cottage3", ID: "cottage3", ParentID: "night phase", Type: discordgo.ChannelTypeGuildVoice, Position: 3, PermissionOverwrites: privateCottage},
+		{Name: "cottage4", ID: "cottage4", ParentID: "night phase", Type: discordgo.ChannelTypeGuildVoice, Position: 4, PermissionOverwrites: privateCottage},
+		{Name: "cottage5", ID: "cottage5", ParentID: "night phase", Type: discordgo.ChannelTypeGuildVoice, Position: 5, PermissionOverwrites: privateCottage},
+	}
+	for n, data := range f.createdChannels {
+		channels = append(channels, &discordgo.Channel{
+			ID:                   fmt.Sprintf("created%d", n+1),
+			Name:                 data.Name,
+			ParentID:             data.ParentID,
+			Type:                 data.Type,
+			Position:             data.Position,
+			PermissionOverwrites: data.PermissionOverwrites,
+		})
+	}
+
+	// Deleted channels are gone.
+	return slices.DeleteFunc(channels, func(channel *discordgo.Channel) bool {
+		return slices.Contains(f.deletedChannels, channel.ID)
+	}), nil
 }
 
 func (f *fakeDiscordSession) StateGuild(guildID string) (*discordgo.Guild, error) {
@@ -40,7 +72,14 @@ func (f *fakeDiscordSession) StateGuild(guildID string) (*discordgo.Guild, error
 		return nil, fmt.Errorf("unknown guild: %v", guildID)
 	}
 
+	// The guild state contains the same channels as the API.
+	channels, _ := f.GuildChannels(guildID)
+	if f.voiceStates != nil {
+		return &discordgo.Guild{Channels: channels, VoiceStates: f.voiceStates}, nil
+	}
+
 	return &discordgo.Guild{
+		Channels: channels,
 		VoiceStates: []*discordgo.VoiceState{
 			{UserID: "user1", ChannelID: "townsquare"},
 			{UserID: "user2", ChannelID: "inn"},
@@ -57,31 +96,137 @@ func (f *fakeDiscordSession) GuildMembers(guildID string, after string, limit in
 	}
 
 	return []*discordgo.Member{
-		{User: &discordgo.User{ID: "user1"}, Roles: []string{"role1"}},
-		{User: &discordgo.User{ID: "user2"}, Roles: []string{"role1"}},
-		{User: &discordgo.User{ID: "user3"}, Roles: []string{"role1"}},
-		{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"role1", "storyteller"}},
+		{User: &discordgo.User{ID: "user1", Username: "User 1"}, Roles: []string{"role1"}},
+		{User: &discordgo.User{ID: "user2", Username: "User 2"}, Roles: []string{"role1"}},
+		{User: &discordgo.User{ID: "user3", Username: "User 3"}, Roles: []string{"role1"}},
+		{User: &discordgo.User{ID: "storyteller", Username: "Story Teller"}, Roles: []string{"role1", "storyteller"}},
 		{User: &discordgo.User{ID: "storyteller2"}, Roles: []string{"role1", "storyteller"}},
 	}, nil
 }
 
 func (f *fakeDiscordSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	f.responses = append(f.responses, resp)
 	return nil
 }
 
+func (f *fakeDiscordSession) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.edits = append(f.edits, newresp)
+	return &discordgo.Message{}, nil
+}
+
 func (f *fakeDiscordSession) GuildRoles(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Role, error) {
 	if f.id == guildID {
-		return []*discordgo.Role{
+		roles := []*discordgo.Role{
 			{ID: "role1", Name: "role1"},
 			{ID: "role2", Name: "role2"},
 			{ID: "role3", Name: "role3"},
 			{ID: "storyteller", Name: "storyteller"},
-		}, nil
+		}
+		for n, data := range f.createdRoles {
+			roles = append(roles, &discordgo.Role{ID: fmt.Sprintf("createdRole%d", n+1), Name: data.Name})
+		}
+		return roles, nil
 	}
 
 	return nil, fmt.Errorf("unknown guild: %v", guildID)
 }
 
+func (f *fakeDiscordSession) GuildChannelCreateComplex(guildID string, data discordgo.GuildChannelCreateData, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	if f.id != guildID {
+		return nil, fmt.Errorf("unknown guild: %v", guildID)
+	}
+
+	f.createdChannels = append(f.createdChannels, data)
+	return &discordgo.Channel{
+		ID:                   fmt.Sprintf("created%d", len(f.createdChannels)),
+		Name:                 data.Name,
+		ParentID:             data.ParentID,
+		Type:                 data.Type,
+		Position:             data.Position,
+		PermissionOverwrites: data.PermissionOverwrites,
+	}, nil
+}
+
+func (f *fakeDiscordSession) GuildRoleCreate(guildID string, data *discordgo.RoleParams, options ...discordgo.RequestOption) (*discordgo.Role, error) {
+	if f.id != guildID {
+		return nil, fmt.Errorf("unknown guild: %v", guildID)
+	}
+
+	f.createdRoles = append(f.createdRoles, data)
+	return &discordgo.Role{ID: fmt.Sprintf("createdRole%d", len(f.createdRoles)), Name: data.Name}, nil
+}
+
+func (f *fakeDiscordSession) ChannelDelete(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	f.deletedChannels = append(f.deletedChannels, channelID)
+	return &discordgo.Channel{ID: channelID}, nil
+}
+
+func (f *fakeDiscordSession) GuildMember(guildID string, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error) {
+	members, err := f.GuildMembers(guildID, "", 1000)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if member.User.ID == userID {
+			return member, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown member: %v", userID)
+}
+
+func (f *fakeDiscordSession) GuildMemberNickname(guildID string, userID string, nickname string, options ...discordgo.RequestOption) error {
+	if f.id != guildID {
+		return fmt.Errorf("unknown guild: %v", guildID)
+	}
+	if f.nicknames == nil {
+		f.nicknames = make(map[string]string)
+	}
+	f.nicknames[userID] = nickname
+	return nil
+}
+
+func (f *fakeDiscordSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.messages = append(f.messages, data)
+	return &discordgo.Message{ID: fmt.Sprintf("message%d", len(f.messages)), ChannelID: channelID, Content: data.Content}, nil
+}
+
+func (f *fakeDiscordSession) ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.messageEdits = append(f.messageEdits, m)
+	message := &discordgo.Message{ID: m.ID, ChannelID: m.Channel}
+	if m.Content != nil {
+		message.Content = *m.Content
+	}
+	return message, nil
+}
+
+func (f *fakeDiscordSession) Download(ctx context.Context, url string) ([]byte, error) {
+	content, ok := f.downloads[url]
+	if !ok {
+		return nil, fmt.Errorf("not found: %s", url)
+	}
+	return content, nil
+}
+
+// privateCottage contains the permission overwrites of all cottages of the fake guild.
+var privateCottage = []*discordgo.PermissionOverwrite{
+	{ID: "everyone", Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionViewChannel},
+}
+
+// newTestBot creates a bot that forwards all dispatched movement plans to the returned channel
+// instead of executing them.
+func newTestBot(cfg *Config) (*Bot, chan *movementPlan) {
+	ch := make(chan *movementPlan, 1)
+	b := &Bot{cfg: cfg, store: newMemoryGameStore(), whispers: newWhisperRegistry(), timers: newTimerRegistry(), joins: newJoinTracker()}
+	b.plans = newPlanDispatcher(func(p *movementPlan) { ch <- p })
+	return b, ch
+}
+
 func TestUserIsStoryTeller(t *testing.T) {
 	m := New(&Config{
 		Tokens:                  []string{"a", "b", "c"},
@@ -134,19 +279,16 @@ func TestUserIsStoryTeller(t *testing.T) {
 }
 
 func TestPrepareDayMoves(t *testing.T) {
-	b := &Bot{
-		ch: make(chan *movementPlan, 1),
-		cfg: &Config{
-			Tokens:                  []string{"a", "b", "c"},
-			NightPhaseCategory:      "night phase",
-			DayPhaseCategory:        "day phase",
-			TownSquare:              "townsquare",
-			StoryTellerRole:         "storyteller",
-			MovementDeadlineSeconds: 15,
-			PerRequestSeconds:       5,
-			MaxConcurrentRequests:   3,
-		},
-	}
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
 
 	d := &fakeDiscordSession{
 		id: "guild",
@@ -171,30 +313,27 @@ func TestPrepareDayMoves(t *testing.T) {
 	}
 
 	select {
-	case plan := <-b.ch:
+	case plan := <-plans:
 		got := plan.moves
 		if diff := cmp.Diff(want, got); diff != "" {
 			t.Fatalf("Movement plan mismatch (-want, +got):%s\n", diff)
 		}
-	default:
+	case <-time.After(time.Second):
 		t.Fatal("Expected to receive plan, got nothing.")
 	}
 }
 
 func TestPrepareNightMoves(t *testing.T) {
-	b := &Bot{
-		ch: make(chan *movementPlan, 1),
-		cfg: &Config{
-			Tokens:                  []string{"a", "b", "c"},
-			NightPhaseCategory:      "night phase",
-			DayPhaseCategory:        "day phase",
-			TownSquare:              "townsquare",
-			StoryTellerRole:         "storyteller",
-			MovementDeadlineSeconds: 15,
-			PerRequestSeconds:       5,
-			MaxConcurrentRequests:   3,
-		},
-	}
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
 
 	d := &fakeDiscordSession{
 		id: "guild",
@@ -212,7 +351,7 @@ func TestPrepareNightMoves(t *testing.T) {
 	}
 
 	select {
-	case plan := <-b.ch:
+	case plan := <-plans:
 		t.Logf("Movement plan: %#v", plan.moves)
 		if len(plan.moves) != 5 {
 			t.Fatalf("Expected 5 movements, got %#v", plan.moves)
@@ -243,7 +382,417 @@ func TestPrepareNightMoves(t *testing.T) {
 			}
 		}
 
-	default:
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+}
+
+func TestPrepareNightMovesStickyCottages(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+
+	d := &fakeDiscordSession{
+		id: "guild",
+	}
+
+	// Remember cottages from a previous night. The library is not a cottage (anymore), so user1
+	// has to take a new one.
+	b.store.Update("guild", func(state *GameState) error {
+		state.Cottages = map[string]string{
+			"user1": "library",
+			"user2": "cottage4",
+			"user3": "cottage2",
+		}
+		return nil
+	})
+
+	ctx := context.Background()
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+		},
+	}
+
+	if err := b.prepareNightMoves(ctx, d, i); err != nil {
+		t.Fatalf("Cannot prepare night moves: %v", err)
+	}
+
+	var plan *movementPlan
+	select {
+	case plan = <-plans:
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+
+	if got := plan.moves["user2"]; got != "cottage4" {
+		t.Errorf("Expected user2 to return to cottage4, got %s", got)
+	}
+	if got := plan.moves["user3"]; got != "cottage2" {
+		t.Errorf("Expected user3 to return to cottage2, got %s", got)
+	}
+	if got := plan.moves["user1"]; got == "cottage2" || got == "cottage4" || !strings.HasPrefix(got, "cottage") {
+		t.Errorf("Expected user1 to take a free cottage, got %s", got)
+	}
+
+	// The assignments of this night are remembered for the next one.
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if diff := cmp.Diff(plan.moves, state.Cottages); diff != "" {
+		t.Errorf("Remembered cottages mismatch (-want, +got):%s\n", diff)
+	}
+
+	if err := b.startGame(ctx, d, i); err != nil {
+		t.Fatalf("Cannot start new game: %v", err)
+	}
+	state, err = b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if len(state.Cottages) != 0 {
+		t.Errorf("Expected new game to forget all cottages, got %#v", state.Cottages)
+	}
+}
+
+func TestPhaseTransitionsUpdateGameState(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+
+	d := &fakeDiscordSession{
+		id: "guild",
+	}
+
+	ctx := context.Background()
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+		},
+	}
+
+	for _, tc := range []struct {
+		prepare   func(context.Context, discordSession, *discordgo.InteractionCreate) error
+		wantPhase string
+		wantDay   int
+	}{
+		{b.prepareNightMoves, phaseNight, 0},
+		{b.prepareDayMoves, phaseDay, 1},
+		{b.prepareDayMoves, phaseDay, 1},
+		{b.prepareNightMoves, phaseNight, 1},
+		{b.prepareDayMoves, phaseDay, 2},
+	} {
+		if err := tc.prepare(ctx, d, i); err != nil {
+			t.Fatalf("Cannot prepare moves: %v", err)
+		}
+		select {
+		case <-plans:
+		case <-time.After(time.Second):
+			t.Fatal("Expected to receive plan, got nothing.")
+		}
+		b.plans.Wait()
+
+		state, err := b.store.Get("guild")
+		if err != nil {
+			t.Fatalf("Cannot load game state: %v", err)
+		}
+		if state.Phase != tc.wantPhase || state.Day != tc.wantDay {
+			t.Fatalf("Expected %s %d, got %s %d", tc.wantPhase, tc.wantDay, state.Phase, state.Day)
+		}
+	}
+}
+
+func TestPrepareNightMovesSkipsNonPlayers(t *testing.T) {
+	for _, tc := range []struct {
+		desc            string
+		spectatorRole   string
+		ignoredChannels []string
+		want            []string
+	}{
+		{
+			desc:          "spectator role",
+			spectatorRole: "role1",
+			// Story tellers are always moved, even if they have the spectator role.
+			want: []string{"storyteller", "storyteller2"},
+		},
+		{
+			desc:            "ignored channels",
+			ignoredChannels: []string{"inn", "barber"},
+			want:            []string{"storyteller", "storyteller2", "user1"},
+		},
+	} {
+		b, plans := newTestBot(&Config{
+			Tokens:                  []string{"a", "b", "c"},
+			NightPhaseCategory:      "night phase",
+			DayPhaseCategory:        "day phase",
+			TownSquare:              "townsquare",
+			StoryTellerRole:         "storyteller",
+			SpectatorRole:           tc.spectatorRole,
+			IgnoredChannels:         tc.ignoredChannels,
+			MovementDeadlineSeconds: 15,
+			PerRequestSeconds:       5,
+			MaxConcurrentRequests:   3,
+		})
+
+		d := &fakeDiscordSession{
+			id: "guild",
+		}
+
+		i := &discordgo.InteractionCreate{
+			Interaction: &discordgo.Interaction{
+				GuildID: "guild",
+			},
+		}
+
+		if err := b.prepareNightMoves(context.Background(), d, i); err != nil {
+			t.Fatalf("%s: Cannot prepare night moves: %v", tc.desc, err)
+		}
+
+		select {
+		case plan := <-plans:
+			var got []string
+			for user := range plan.moves {
+				got = append(got, user)
+			}
+			slices.Sort(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s: Moved users mismatch (-want, +got):%s\n", tc.desc, diff)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: Expected to receive plan, got nothing.", tc.desc)
+		}
+	}
+}
+
+func TestSkipAtNightOutsideDayCategory(t *testing.T) {
+	vs := &discordVoiceState{
+		channels: map[string]*discordgo.Channel{
+			"townsquare": {ID: "townsquare", ParentID: "day phase"},
+			"inn":        {ID: "inn", ParentID: "day phase"},
+			"music":      {ID: "music", ParentID: "lounge"},
+		},
+		dayCategory:       &discordgo.Channel{ID: "day phase"},
+		storyTellerRoleID: "storyteller",
+	}
+	player := &discordgo.Member{User: &discordgo.User{ID: "player"}}
+	storyTeller := &discordgo.Member{User: &discordgo.User{ID: "st"}, Roles: []string{"storyteller"}}
+
+	for _, tc := range []struct {
+		dayCategoryOnly bool
+		member          *discordgo.Member
+		channel         string
+		want            bool
+	}{
+		{false, player, "music", false},
+		{true, player, "townsquare", false},
+		{true, player, "inn", false},
+		{true, player, "music", true},
+		{true, player, "unknown", true},
+		{true, storyTeller, "music", false},
+	} {
+		cfg := &Config{NightMovesFromDayCategoryOnly: tc.dayCategoryOnly}
+		if got := skipAtNight(cfg, vs, tc.member, tc.channel); got != tc.want {
+			t.Errorf("skipAtNight(day category only: %t, %s, %s) = %t, want %t", tc.dayCategoryOnly, tc.member.User.ID, tc.channel, got, tc.want)
+		}
+	}
+}
+
+func TestPhaseMutes(t *testing.T) {
+	for _, tc := range []struct {
+		desc        string
+		cottageMute string
+		phase       string
+		want        map[string]bool
+	}{
+		{
+			desc:  "night without cottage mute",
+			phase: phaseNight,
+		},
+		{
+			desc:        "night with cottage mute",
+			cottageMute: cottageMuteOn,
+			phase:       phaseNight,
+			// Story tellers are never muted, user2 already is.
+			want: map[string]bool{"user1": true, "user3": true},
+		},
+		{
+			desc:        "night with cottage unmute",
+			cottageMute: cottageMuteOff,
+			phase:       phaseNight,
+			want:        map[string]bool{"user2": false},
+		},
+		{
+			desc:        "day",
+			cottageMute: cottageMuteOn,
+			phase:       phaseDay,
+			want:        map[string]bool{"user2": false, "storyteller": false},
+		},
+	} {
+		b, plans := newTestBot(&Config{
+			Tokens:                  []string{"a", "b", "c"},
+			NightPhaseCategory:      "night phase",
+			DayPhaseCategory:        "day phase",
+			TownSquare:              "townsquare",
+			StoryTellerRole:         "storyteller",
+			MovementDeadlineSeconds: 15,
+			PerRequestSeconds:       5,
+			MaxConcurrentRequests:   3,
+			CottageMute:             tc.cottageMute,
+		})
+
+		d := &fakeDiscordSession{
+			id: "guild",
+			voiceStates: []*discordgo.VoiceState{
+				{UserID: "user1", ChannelID: "townsquare"},
+				{UserID: "user2", ChannelID: "inn", Mute: true},
+				{UserID: "user3", ChannelID: "cottage3"},
+				{UserID: "storyteller", ChannelID: "barber", Mute: true},
+			},
+		}
+		i := &discordgo.InteractionCreate{
+			Interaction: &discordgo.Interaction{
+				GuildID: "guild",
+				Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}},
+			},
+		}
+
+		prepare := b.prepareNightMoves
+		if tc.phase == phaseDay {
+			prepare = b.prepareDayMoves
+		}
+		if err := prepare(context.Background(), d, i); err != nil {
+			t.Fatalf("%s: Cannot prepare moves: %v", tc.desc, err)
+		}
+
+		select {
+		case plan := <-plans:
+			if diff := cmp.Diff(tc.want, plan.mutes); diff != "" {
+				t.Errorf("%s: Mutes mismatch (-want, +got):%s\n", tc.desc, diff)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: Expected to receive plan, got nothing.", tc.desc)
+		}
+	}
+}
+
+func TestSilenceTownSquare(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user1", ChannelID: "townsquare"},
+			{UserID: "user2", ChannelID: "townsquare", Mute: true},
+			{UserID: "user3", ChannelID: "inn"},
+			{UserID: "storyteller", ChannelID: "townsquare"},
+		},
+	}
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+		},
+	}
+
+	if err := b.silenceTownSquare(context.Background(), d, i); err != nil {
+		t.Fatalf("Cannot silence Town Square: %v", err)
+	}
+
+	select {
+	case plan := <-plans:
+		if len(plan.moves) != 0 {
+			t.Errorf("Expected no moves, got %v", plan.moves)
+		}
+		if diff := cmp.Diff(map[string]bool{"user1": true}, plan.mutes); diff != "" {
+			t.Errorf("Mutes mismatch (-want, +got):%s\n", diff)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	// Nothing to do if everyone is muted already.
+	d.voiceStates[0].Mute = true
+	d.responses = nil
+	if err := b.silenceTownSquare(context.Background(), d, i); err != nil {
+		t.Fatalf("Cannot silence Town Square: %v", err)
+	}
+	if got := d.responses[0].Data.Content; !strings.Contains(got, "already muted") {
+		t.Errorf("Unexpected response %q", got)
+	}
+}
+
+func TestDayUnmutesUnseatedMembers(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"user1"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	// Town Square was silenced, user2 and user3 are not seated.
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user1", ChannelID: "townsquare", Mute: true},
+			{UserID: "user2", ChannelID: "townsquare", Mute: true},
+			{UserID: "user3", ChannelID: "cottage1", Mute: true},
+		},
+	}
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+		},
+	}
+	if err := b.prepareDayMoves(context.Background(), d, i); err != nil {
+		t.Fatalf("Cannot prepare day moves: %v", err)
+	}
+
+	select {
+	case plan := <-plans:
+		if len(plan.moves) != 0 {
+			t.Errorf("Expected no moves, got %v", plan.moves)
+		}
+		// Unseated members are only un-muted in the day category.
+		if diff := cmp.Diff(map[string]bool{"user1": false, "user2": false}, plan.mutes); diff != "" {
+			t.Errorf("Mutes mismatch (-want, +got):%s\n", diff)
+		}
+	case <-time.After(time.Second):
 		t.Fatal("Expected to receive plan, got nothing.")
 	}
+	b.plans.Wait()
 }
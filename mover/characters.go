@@ -0,0 +1,211 @@
+package mover
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Character teams.
+const (
+	teamTownsfolk = "townsfolk"
+	teamOutsider  = "outsider"
+	teamMinion    = "minion"
+	teamDemon     = "demon"
+	teamTraveller = "traveller"
+)
+
+// teams contains all character teams in the order they are listed on a script.
+var teams = []string{teamTownsfolk, teamOutsider, teamMinion, teamDemon, teamTraveller}
+
+// character is a character of the official editions.
+type character struct {
+	id   string
+	name string
+	team string
+}
+
+// characters contains all characters the bot knows about, i.e. the characters of Trouble
+// Brewing, Bad Moon Rising and Sects & Violets along with their travellers.
+var characters = []*character{
+	// Trouble Brewing.
+	{"washerwoman", "Washerwoman", teamTownsfolk},
+	{"librarian", "Librarian", teamTownsfolk},
+	{"investigator", "Investigator", teamTownsfolk},
+	{"chef", "Chef", teamTownsfolk},
+	{"empath", "Empath", teamTownsfolk},
+	{"fortuneteller", "Fortune Teller", teamTownsfolk},
+	{"undertaker", "Undertaker", teamTownsfolk},
+	{"monk", "Monk", teamTownsfolk},
+	{"ravenkeeper", "Ravenkeeper", teamTownsfolk},
+	{"virgin", "Virgin", teamTownsfolk},
+	{"slayer", "Slayer", teamTownsfolk},
+	{"soldier", "Soldier", teamTownsfolk},
+	{"mayor", "Mayor", teamTownsfolk},
+	{"butler", "Butler", teamOutsider},
+	{"drunk", "Drunk", teamOutsider},
+	{"recluse", "Recluse", teamOutsider},
+	{"saint", "Saint", teamOutsider},
+	{"poisoner", "Poisoner", teamMinion},
+	{"spy", "Spy", teamMinion},
+	{"scarletwoman", "Scarlet Woman", teamMinion},
+	{"baron", "Baron", teamMinion},
+	{"imp", "Imp", teamDemon},
+	{"bureaucrat", "Bureaucrat", teamTraveller},
+	{"thief", "Thief", teamTraveller},
+	{"gunslinger", "Gunslinger", teamTraveller},
+	{"scapegoat", "Scapegoat", teamTraveller},
+	{"beggar", "Beggar", teamTraveller},
+
+	// Bad Moon Rising.
+	{"grandmother", "Grandmother", teamTownsfolk},
+	{"sailor", "Sailor", teamTownsfolk},
+	{"chambermaid", "Chambermaid", teamTownsfolk},
+	{"exorcist", "Exorcist", teamTownsfolk},
+	{"innkeeper", "Innkeeper", teamTownsfolk},
+	{"gambler", "Gambler", teamTownsfolk},
+	{"gossip", "Gossip", teamTownsfolk},
+	{"courtier", "Courtier", teamTownsfolk},
+	{"professor", "Professor", teamTownsfolk},
+	{"minstrel", "Minstrel", teamTownsfolk},
+	{"tealady", "Tea Lady", teamTownsfolk},
+	{"pacifist", "Pacifist", teamTownsfolk},
+	{"fool", "Fool", teamTownsfolk},
+	{"tinker", "Tinker", teamOutsider},
+	{"moonchild", "Moonchild", teamOutsider},
+	{"goon", "Goon", teamOutsider},
+	{"lunatic", "Lunatic", teamOutsider},
+	{"godfather", "Godfather", teamMinion},
+	{"devilsadvocate", "Devil's Advocate", teamMinion},
+	{"assassin", "Assassin", teamMinion},
+	{"mastermind", "Mastermind", teamMinion},
+	{"zombuul", "Zombuul", teamDemon},
+	{"pukka", "Pukka", teamDemon},
+	{"shabaloth", "Shabaloth", teamDemon},
+	{"po", "Po", teamDemon},
+	{"apprentice", "Apprentice", teamTraveller},
+	{"matron", "Matron", teamTraveller},
+	{"judge", "Judge", teamTraveller},
+	{"bishop", "Bishop", teamTraveller},
+	{"voudon", "Voudon", teamTraveller},
+
+	// Sects & Violets.
+	{"clockmaker", "Clockmaker", teamTownsfolk},
+	{"dreamer", "Dreamer", teamTownsfolk},
+	{"snakecharmer", "Snake Charmer", teamTownsfolk},
+	{"mathematician", "Mathematician", teamTownsfolk},
+	{"flowergirl", "Flowergirl", teamTownsfolk},
+	{"towncrier", "Town Crier", teamTownsfolk},
+	{"oracle", "Oracle", teamTownsfolk},
+	{"savant", "Savant", teamTownsfolk},
+	{"seamstress", "Seamstress", teamTownsfolk},
+	{"philosopher", "Philosopher", teamTownsfolk},
+	{"artist", "Artist", teamTownsfolk},
+	{"juggler", "Juggler", teamTownsfolk},
+	{"sage", "Sage", teamTownsfolk},
+	{"mutant", "Mutant", teamOutsider},
+	{"sweetheart", "Sweetheart", teamOutsider},
+	{"barber", "Barber", teamOutsider},
+	{"klutz", "Klutz", teamOutsider},
+	{"eviltwin", "Evil Twin", teamMinion},
+	{"witch", "Witch", teamMinion},
+	{"cerenovus", "Cerenovus", teamMinion},
+	{"pithag", "Pit-Hag", teamMinion},
+	{"fanggu", "Fang Gu", teamDemon},
+	{"vigormortis", "Vigormortis", teamDemon},
+	{"nodashii", "No Dashii", teamDemon},
+	{"vortox", "Vortox", teamDemon},
+	{"barista", "Barista", teamTraveller},
+	{"harlot", "Harlot", teamTraveller},
+	{"butcher", "Butcher", teamTraveller},
+	{"bonecollector", "Bone Collector", teamTraveller},
+	{"deviant", "Deviant", teamTraveller},
+}
+
+// firstNightOrder contains the IDs of all characters who wake on the first night in official night
+// order.
+var firstNightOrder = []string{
+	"apprentice", "barista", "bureaucrat", "thief", "philosopher", "lunatic", "sailor", "poisoner",
+	"courtier", "snakecharmer", "godfather", "devilsadvocate", "eviltwin", "witch", "cerenovus",
+	"pukka", "washerwoman", "librarian", "investigator", "chef", "empath", "fortuneteller", "butler",
+	"grandmother", "clockmaker", "dreamer", "seamstress", "spy", "chambermaid", "mathematician",
+}
+
+// otherNightOrder contains the IDs of all characters who may wake on other nights in official
+// night order.
+var otherNightOrder = []string{
+	"barista", "bureaucrat", "thief", "harlot", "bonecollector", "philosopher", "sailor", "poisoner",
+	"courtier", "innkeeper", "gambler", "snakecharmer", "monk", "devilsadvocate", "witch",
+	"cerenovus", "pithag", "scarletwoman", "lunatic", "exorcist", "imp", "zombuul", "pukka",
+	"shabaloth", "po", "fanggu", "nodashii", "vortox", "vigormortis", "assassin", "godfather",
+	"gossip", "barber", "sweetheart", "sage", "professor", "tinker", "moonchild", "grandmother",
+	"ravenkeeper", "empath", "fortuneteller", "undertaker", "dreamer", "flowergirl", "towncrier",
+	"oracle", "seamstress", "juggler", "butler", "spy", "chambermaid", "mathematician",
+}
+
+// normalizeCharacterID normalizes the ID or name of a character. The Script Tool, clocktower.online
+// and people spell them differently, e.g. "fortune_teller", "fortuneteller" or "Fortune Teller".
+func normalizeCharacterID(id string) string {
+	return strings.Map(func(r rune) rune {
+		if !unicode.IsLetter(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, id)
+}
+
+// findCharacter returns the character with the given ID or name, or nil if there is none.
+func findCharacter(id string) *character {
+	id = normalizeCharacterID(id)
+	for _, c := range characters {
+		if c.id == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// setupCounts contains the number of townsfolk, outsiders, minions and demons for 5 to 15 players
+// in a game without setup modifications, not counting travellers.
+var setupCounts = [][4]int{
+	{3, 0, 1, 1},
+	{3, 1, 1, 1},
+	{5, 0, 1, 1},
+	{5, 1, 1, 1},
+	{5, 2, 1, 1},
+	{7, 0, 2, 1},
+	{7, 1, 2, 1},
+	{7, 2, 2, 1},
+	{9, 0, 3, 1},
+	{9, 1, 3, 1},
+	{9, 2, 3, 1},
+}
+
+// outsiderModifier is the range of Outsiders a character adds to the setup. Townsfolk make up
+// for the difference.
+type outsiderModifier struct {
+	min, max int
+	text     string
+}
+
+// outsiderModifiers contains all characters that modify the number of Outsiders in the setup.
+var outsiderModifiers = map[string]outsiderModifier{
+	"baron":       {2, 2, "+2 Outsiders"},
+	"godfather":   {-1, 1, "-1 or +1 Outsider"},
+	"fanggu":      {1, 1, "+1 Outsider"},
+	"vigormortis": {-1, -1, "-1 Outsider"},
+}
+
+// setup returns the number of characters of every team for the given number of players, not
+// counting travellers. Returns false if there are too few or too many players.
+func setup(players int) (map[string]int, bool) {
+	if players < 5 || players >= 5+len(setupCounts) {
+		return nil, false
+	}
+	counts := setupCounts[players-5]
+	return map[string]int{
+		teamTownsfolk: counts[0],
+		teamOutsider:  counts[1],
+		teamMinion:    counts[2],
+		teamDemon:     counts[3],
+	}, true
+}
@@ -0,0 +1,75 @@
+package mover
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindCharacter(t *testing.T) {
+	for _, tc := range []struct {
+		id   string
+		want string
+	}{
+		{"fortuneteller", "Fortune Teller"},
+		{"fortune_teller", "Fortune Teller"},
+		{"Fortune Teller", "Fortune Teller"},
+		{"Devil's Advocate", "Devil's Advocate"},
+		{"pit-hag", "Pit-Hag"},
+		{"nobody", ""},
+	} {
+		var got string
+		if c := findCharacter(tc.id); c != nil {
+			got = c.name
+		}
+		if got != tc.want {
+			t.Errorf("findCharacter(%q) = %q, want %q", tc.id, got, tc.want)
+		}
+	}
+}
+
+func TestCharacterIDsAreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, c := range characters {
+		if seen[c.id] {
+			t.Errorf("Character %s is listed more than once", c.id)
+		}
+		if c.id != normalizeCharacterID(c.id) {
+			t.Errorf("Character ID %s is not normalized", c.id)
+		}
+		seen[c.id] = true
+	}
+}
+
+func TestNightOrdersContainKnownCharacters(t *testing.T) {
+	for _, order := range [][]string{firstNightOrder, otherNightOrder} {
+		seen := make(map[string]bool)
+		for _, id := range order {
+			if findCharacter(id) == nil || seen[id] {
+				t.Errorf("Night order contains unknown or duplicate character %s", id)
+			}
+			seen[id] = true
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	for _, tc := range []struct {
+		players int
+		want    map[string]int
+	}{
+		{4, nil},
+		{5, map[string]int{teamTownsfolk: 3, teamOutsider: 0, teamMinion: 1, teamDemon: 1}},
+		{9, map[string]int{teamTownsfolk: 5, teamOutsider: 2, teamMinion: 1, teamDemon: 1}},
+		{13, map[string]int{teamTownsfolk: 9, teamOutsider: 0, teamMinion: 3, teamDemon: 1}},
+		{16, nil},
+	} {
+		got, ok := setup(tc.players)
+		if ok != (tc.want != nil) {
+			t.Errorf("setup(%d) returned %t, want %t", tc.players, ok, tc.want != nil)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("setup(%d) mismatch (-want, +got):%s\n", tc.players, diff)
+		}
+	}
+}
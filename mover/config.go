@@ -19,9 +19,32 @@ import (
   "DayPhaseCategory": "Day Phase",
   "TownSquare": "Town Square",
   "StoryTellerRole": "Storyteller",
+  "TownSquareID": "123456789012345678",
   "MovementDeadlineSeconds": 15,
   "PerRequestSeconds": 5,
-  "MaxConcurrentRequests": 3
+  "MaxConcurrentRequests": 3,
+  "MaxCottages": 0,
+  "AutoCreateCottages": true,
+  "CottageNameTemplate": "Cottage {n}",
+  "CottageMute": "mute",
+  "CottagesInNightOrder": true,
+  "SpectatorRole": "Spectator",
+  "IgnoredChannels": ["AFK", "Music"],
+  "NightMovesFromDayCategoryOnly": true,
+  "WhisperSeconds": 180,
+  "MaxWhisperParticipants": 3,
+  "MaxWhispersPerDay": 0,
+  "TimerWarnings": [60, 10],
+  "DeadNicknamePrefix": "💀 ",
+  "StateFile": "/var/lib/botc/games.json",
+  "Guilds": {
+    "<guild ID>": {
+      "NightPhaseCategory": "Cottages",
+      "StoryTellerRole": "ST",
+      "MaxCottages": 12,
+      "MovementDeadlineSeconds": 30
+    }
+  }
 }
 */
 // The config can also be loaded from the following environment variables:
@@ -30,26 +53,341 @@ import (
 // BOTC_DAY_PHASE_CATEGORY
 // BOTC_TOWN_SQUARE
 // BOTC_STORY_TELLER_ROLE
+// BOTC_NIGHT_PHASE_CATEGORY_ID
+// BOTC_DAY_PHASE_CATEGORY_ID
+// BOTC_TOWN_SQUARE_ID
+// BOTC_STORY_TELLER_ROLE_ID
 // BOTC_MOVEMENT_DEADLINE_SECONDS (default 15)
 // BOTC_PER_REQUEST_SECONDS (default 5)
 // BOTC_MAX_CONCURRENT_REQUESTS (default 3)
+// BOTC_MAX_COTTAGES (default 0, i.e. use all cottages)
+// BOTC_AUTO_CREATE_COTTAGES (default false)
+// BOTC_COTTAGE_NAME_TEMPLATE (default "Cottage {n}")
+// BOTC_COTTAGE_MUTE (default empty, i.e. mutes are left alone at night)
+// BOTC_COTTAGES_IN_NIGHT_ORDER (default false)
+// BOTC_SPECTATOR_ROLE
+// BOTC_SPECTATOR_ROLE_ID
+// BOTC_IGNORED_CHANNELS (comma separated channel names or IDs)
+// BOTC_NIGHT_MOVES_FROM_DAY_CATEGORY_ONLY (default false)
+// BOTC_WHISPER_SECONDS (default 180, 0 means whispers have no time limit)
+// BOTC_MAX_WHISPER_PARTICIPANTS (default 0, i.e. no limit)
+// BOTC_MAX_WHISPERS_PER_DAY (default 0, i.e. no limit)
+// BOTC_TIMER_WARNINGS (comma separated seconds, default "60,10")
+// BOTC_DEAD_NICKNAME_PREFIX (default empty, i.e. nicknames are not changed)
+// BOTC_STATE_FILE (default empty, i.e. games are lost on restart)
+// BOTC_GUILD_<guild ID>_<setting> (per-guild override, e.g. BOTC_GUILD_1234_TOWN_SQUARE)
+//
+// Channels and roles are matched by name unless their ID is configured. IDs take precedence over
+// names, which allows renaming channels and roles or having multiple channels with the same name.
+//
+// If AutoCreateCottages is set, missing cottages are created at night. They copy the permissions
+// of an existing cottage and are named after CottageNameTemplate, where {n} is replaced with the
+// cottage number. Use /game cleanup to delete them after the game.
+//
+// If CottageMute is "mute" or "unmute", cottage occupants other than story tellers are server-muted
+// or un-muted once the night moves are done. Everyone is un-muted at day.
+//
+// Seated players sleep in the cottage of their seat. If CottagesInNightOrder is set, players whose
+// character wakes tonight according to /grimoire take the first cottages in night order instead.
+//
+// Members with the spectator role and members in ignored channels are never moved into cottages.
+// If NightMovesFromDayCategoryOnly is set, only members in the day phase category are moved into
+// cottages at night.
+//
+// Whispers started with /whisper end after WhisperSeconds, which returns their players to Town
+// Square. MaxWhisperParticipants and MaxWhispersPerDay limit the whispers that can be started with
+// /whisper. Every conversation in a side room counts towards the daily limit of its players.
+//
+// Timers started with /timer warn when TimerWarnings seconds are left. An empty list disables the
+// warnings.
+//
+// If DeadNicknamePrefix is set, players marked dead with /kill get the prefix in front of their
+// nickname until they are revived or the game ends.
+//
+// Guilds contains per-guild overrides keyed by guild ID. Unset fields of a guild fall back to the
+// global settings.
 type Config struct {
 	Tokens                  []string
 	NightPhaseCategory      string
 	DayPhaseCategory        string
 	TownSquare              string
 	StoryTellerRole         string
+	NightPhaseCategoryID    string
+	DayPhaseCategoryID      string
+	TownSquareID            string
+	StoryTellerRoleID       string
 	MovementDeadlineSeconds int
 	PerRequestSeconds       int
 	MaxConcurrentRequests   int
+	MaxCottages             int
+	AutoCreateCottages      bool
+	CottageNameTemplate     string
+	CottageMute             string
+	CottagesInNightOrder    bool
+	StateFile               string
+
+	SpectatorRole                 string
+	SpectatorRoleID               string
+	IgnoredChannels               []string
+	NightMovesFromDayCategoryOnly bool
+	WhisperSeconds                int
+	MaxWhisperParticipants        int
+	MaxWhispersPerDay             int
+	TimerWarnings                 []int
+	DeadNicknamePrefix            string
+
+	Guilds map[string]*GuildConfig
+}
+
+// GuildConfig overrides the global config for a single guild. Empty fields are inherited from
+// the global config. Settings where zero or empty is meaningful, e.g. MaxWhispersPerDay, are
+// pointers, so that a guild can override them with zero or empty values.
+type GuildConfig struct {
+	NightPhaseCategory      string
+	DayPhaseCategory        string
+	TownSquare              string
+	StoryTellerRole         string
+	NightPhaseCategoryID    string
+	DayPhaseCategoryID      string
+	TownSquareID            string
+	StoryTellerRoleID       string
+	MovementDeadlineSeconds int
+	PerRequestSeconds       int
+	MaxCottages             *int
+	AutoCreateCottages      *bool
+	CottageNameTemplate     string
+	CottageMute             *string
+	CottagesInNightOrder    *bool
+
+	SpectatorRole                 string
+	SpectatorRoleID               string
+	IgnoredChannels               []string
+	NightMovesFromDayCategoryOnly *bool
+	WhisperSeconds                *int
+	MaxWhisperParticipants        *int
+	MaxWhispersPerDay             *int
+	TimerWarnings                 []int
+	DeadNicknamePrefix            *string
 }
 
+// ForGuild returns the effective config for the guild, i.e. the global config with all overrides
+// of the guild applied.
+func (c *Config) ForGuild(guildID string) *Config {
+	cfg := *c
+	cfg.Guilds = nil
+
+	g, ok := c.Guilds[guildID]
+	if !ok || g == nil {
+		return &cfg
+	}
+
+	// IDs take precedence over names, so a guild that names a channel or role must not inherit
+	// the global ID of it.
+	for _, o := range []struct {
+		name, id       *string
+		srcName, srcID string
+	}{
+		{&cfg.NightPhaseCategory, &cfg.NightPhaseCategoryID, g.NightPhaseCategory, g.NightPhaseCategoryID},
+		{&cfg.DayPhaseCategory, &cfg.DayPhaseCategoryID, g.DayPhaseCategory, g.DayPhaseCategoryID},
+		{&cfg.TownSquare, &cfg.TownSquareID, g.TownSquare, g.TownSquareID},
+		{&cfg.StoryTellerRole, &cfg.StoryTellerRoleID, g.StoryTellerRole, g.StoryTellerRoleID},
+		{&cfg.SpectatorRole, &cfg.SpectatorRoleID, g.SpectatorRole, g.SpectatorRoleID},
+	} {
+		if o.srcName != "" {
+			*o.name = o.srcName
+			*o.id = ""
+		}
+		if o.srcID != "" {
+			*o.id = o.srcID
+		}
+	}
+	if g.CottageNameTemplate != "" {
+		cfg.CottageNameTemplate = g.CottageNameTemplate
+	}
+	for _, o := range []struct {
+		dst *string
+		src *string
+	}{
+		{&cfg.CottageMute, g.CottageMute},
+		{&cfg.DeadNicknamePrefix, g.DeadNicknamePrefix},
+	} {
+		if o.src != nil {
+			*o.dst = *o.src
+		}
+	}
+	if g.MovementDeadlineSeconds != 0 {
+		cfg.MovementDeadlineSeconds = g.MovementDeadlineSeconds
+	}
+	if g.PerRequestSeconds != 0 {
+		cfg.PerRequestSeconds = g.PerRequestSeconds
+	}
+	for _, o := range []struct {
+		dst *int
+		src *int
+	}{
+		{&cfg.MaxCottages, g.MaxCottages},
+		{&cfg.WhisperSeconds, g.WhisperSeconds},
+		{&cfg.MaxWhisperParticipants, g.MaxWhisperParticipants},
+		{&cfg.MaxWhispersPerDay, g.MaxWhispersPerDay},
+	} {
+		if o.src != nil {
+			*o.dst = *o.src
+		}
+	}
+	if g.IgnoredChannels != nil {
+		cfg.IgnoredChannels = g.IgnoredChannels
+	}
+	if g.TimerWarnings != nil {
+		cfg.TimerWarnings = g.TimerWarnings
+	}
+	if g.AutoCreateCottages != nil {
+		cfg.AutoCreateCottages = *g.AutoCreateCottages
+	}
+	if g.NightMovesFromDayCategoryOnly != nil {
+		cfg.NightMovesFromDayCategoryOnly = *g.NightMovesFromDayCategoryOnly
+	}
+	if g.CottagesInNightOrder != nil {
+		cfg.CottagesInNightOrder = *g.CottagesInNightOrder
+	}
+
+	return &cfg
+}
+
+// Values of CottageMute.
+const (
+	cottageMuteOn  = "mute"
+	cottageMuteOff = "unmute"
+)
+
+// guildEnvPrefix is the prefix of all environment variables that override settings for a single
+// guild, e.g. BOTC_GUILD_1234_TOWN_SQUARE.
+const guildEnvPrefix = "BOTC_GUILD_"
+
+// guildConfigsFromEnv loads all per-guild overrides from environment variables.
+func guildConfigsFromEnv() (map[string]*GuildConfig, error) {
+	guilds := make(map[string]*GuildConfig)
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		rest, ok := strings.CutPrefix(key, guildEnvPrefix)
+		if !ok {
+			continue
+		}
+		guildID, setting, ok := strings.Cut(rest, "_")
+		if !ok || guildID == "" {
+			return nil, fmt.Errorf("invalid guild setting %s", key)
+		}
+
+		g, ok := guilds[guildID]
+		if !ok {
+			g = &GuildConfig{}
+			guilds[guildID] = g
+		}
+
+		var err error
+		switch setting {
+		case "NIGHT_PHASE_CATEGORY":
+			g.NightPhaseCategory = value
+		case "DAY_PHASE_CATEGORY":
+			g.DayPhaseCategory = value
+		case "TOWN_SQUARE":
+			g.TownSquare = value
+		case "STORY_TELLER_ROLE":
+			g.StoryTellerRole = value
+		case "NIGHT_PHASE_CATEGORY_ID":
+			g.NightPhaseCategoryID = value
+		case "DAY_PHASE_CATEGORY_ID":
+			g.DayPhaseCategoryID = value
+		case "TOWN_SQUARE_ID":
+			g.TownSquareID = value
+		case "STORY_TELLER_ROLE_ID":
+			g.StoryTellerRoleID = value
+		case "MOVEMENT_DEADLINE_SECONDS":
+			g.MovementDeadlineSeconds, err = strconv.Atoi(value)
+		case "PER_REQUEST_SECONDS":
+			g.PerRequestSeconds, err = strconv.Atoi(value)
+		case "MAX_COTTAGES":
+			var v int
+			v, err = strconv.Atoi(value)
+			g.MaxCottages = &v
+		case "AUTO_CREATE_COTTAGES":
+			var v bool
+			v, err = strconv.ParseBool(value)
+			g.AutoCreateCottages = &v
+		case "COTTAGE_NAME_TEMPLATE":
+			g.CottageNameTemplate = value
+		case "COTTAGE_MUTE":
+			g.CottageMute = &value
+		case "COTTAGES_IN_NIGHT_ORDER":
+			var v bool
+			v, err = strconv.ParseBool(value)
+			g.CottagesInNightOrder = &v
+		case "SPECTATOR_ROLE":
+			g.SpectatorRole = value
+		case "SPECTATOR_ROLE_ID":
+			g.SpectatorRoleID = value
+		case "IGNORED_CHANNELS":
+			g.IgnoredChannels = strings.Split(value, ",")
+		case "NIGHT_MOVES_FROM_DAY_CATEGORY_ONLY":
+			var v bool
+			v, err = strconv.ParseBool(value)
+			g.NightMovesFromDayCategoryOnly = &v
+		case "WHISPER_SECONDS":
+			var v int
+			v, err = strconv.Atoi(value)
+			g.WhisperSeconds = &v
+		case "MAX_WHISPER_PARTICIPANTS":
+			var v int
+			v, err = strconv.Atoi(value)
+			g.MaxWhisperParticipants = &v
+		case "MAX_WHISPERS_PER_DAY":
+			var v int
+			v, err = strconv.Atoi(value)
+			g.MaxWhispersPerDay = &v
+		case "TIMER_WARNINGS":
+			g.TimerWarnings, err = parseSeconds(value)
+		case "DEAD_NICKNAME_PREFIX":
+			g.DeadNicknamePrefix = &value
+		default:
+			return nil, fmt.Errorf("unknown guild setting %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+
+	if len(guilds) == 0 {
+		return nil, nil
+	}
+	return guilds, nil
+}
+
+// parseSeconds parses a comma separated list of seconds. An empty value is an empty list.
+func parseSeconds(value string) ([]int, error) {
+	seconds := []int{}
+	if value == "" {
+		return seconds, nil
+	}
+	for _, v := range strings.Split(value, ",") {
+		d, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, err
+		}
+		seconds = append(seconds, d)
+	}
+	return seconds, nil
+}
+
+// DefaultWhisperSeconds is the default time limit of whispers. Configs need to set WhisperSeconds
+// to 0 explicitly to allow whispers without a time limit.
+const DefaultWhisperSeconds = 180
+
 // ConfigFromEnv loads a config from environment variables with reasonable defaults.
 func ConfigFromEnv() (*Config, error) {
 	cfg := &Config{
 		MovementDeadlineSeconds: 15,
 		PerRequestSeconds:       5,
 		MaxConcurrentRequests:   3,
+		WhisperSeconds:          DefaultWhisperSeconds,
 	}
 
 	if v, ok := os.LookupEnv("BOTC_TOKENS"); ok {
@@ -67,6 +405,18 @@ func ConfigFromEnv() (*Config, error) {
 	if v, ok := os.LookupEnv("BOTC_STORY_TELLER_ROLE"); ok {
 		cfg.StoryTellerRole = v
 	}
+	if v, ok := os.LookupEnv("BOTC_NIGHT_PHASE_CATEGORY_ID"); ok {
+		cfg.NightPhaseCategoryID = v
+	}
+	if v, ok := os.LookupEnv("BOTC_DAY_PHASE_CATEGORY_ID"); ok {
+		cfg.DayPhaseCategoryID = v
+	}
+	if v, ok := os.LookupEnv("BOTC_TOWN_SQUARE_ID"); ok {
+		cfg.TownSquareID = v
+	}
+	if v, ok := os.LookupEnv("BOTC_STORY_TELLER_ROLE_ID"); ok {
+		cfg.StoryTellerRoleID = v
+	}
 	if v, ok := os.LookupEnv("BOTC_MOVEMENT_DEADLINE_SECONDS"); ok {
 		if d, err := strconv.Atoi(v); err != nil {
 			return nil, err
@@ -88,6 +438,91 @@ func ConfigFromEnv() (*Config, error) {
 			cfg.MaxConcurrentRequests = d
 		}
 	}
+	if v, ok := os.LookupEnv("BOTC_MAX_COTTAGES"); ok {
+		if d, err := strconv.Atoi(v); err != nil {
+			return nil, err
+		} else {
+			cfg.MaxCottages = d
+		}
+	}
+
+	if v, ok := os.LookupEnv("BOTC_AUTO_CREATE_COTTAGES"); ok {
+		if d, err := strconv.ParseBool(v); err != nil {
+			return nil, err
+		} else {
+			cfg.AutoCreateCottages = d
+		}
+	}
+	if v, ok := os.LookupEnv("BOTC_COTTAGE_NAME_TEMPLATE"); ok {
+		cfg.CottageNameTemplate = v
+	}
+	if v, ok := os.LookupEnv("BOTC_COTTAGE_MUTE"); ok {
+		cfg.CottageMute = v
+	}
+	if v, ok := os.LookupEnv("BOTC_WHISPER_SECONDS"); ok {
+		if d, err := strconv.Atoi(v); err != nil {
+			return nil, err
+		} else {
+			cfg.WhisperSeconds = d
+		}
+	}
+	if v, ok := os.LookupEnv("BOTC_MAX_WHISPER_PARTICIPANTS"); ok {
+		if d, err := strconv.Atoi(v); err != nil {
+			return nil, err
+		} else {
+			cfg.MaxWhisperParticipants = d
+		}
+	}
+	if v, ok := os.LookupEnv("BOTC_MAX_WHISPERS_PER_DAY"); ok {
+		if d, err := strconv.Atoi(v); err != nil {
+			return nil, err
+		} else {
+			cfg.MaxWhispersPerDay = d
+		}
+	}
+	if v, ok := os.LookupEnv("BOTC_TIMER_WARNINGS"); ok {
+		if d, err := parseSeconds(v); err != nil {
+			return nil, err
+		} else {
+			cfg.TimerWarnings = d
+		}
+	}
+	if v, ok := os.LookupEnv("BOTC_DEAD_NICKNAME_PREFIX"); ok {
+		cfg.DeadNicknamePrefix = v
+	}
+	if v, ok := os.LookupEnv("BOTC_STATE_FILE"); ok {
+		cfg.StateFile = v
+	}
+	if v, ok := os.LookupEnv("BOTC_SPECTATOR_ROLE"); ok {
+		cfg.SpectatorRole = v
+	}
+	if v, ok := os.LookupEnv("BOTC_SPECTATOR_ROLE_ID"); ok {
+		cfg.SpectatorRoleID = v
+	}
+	if v, ok := os.LookupEnv("BOTC_IGNORED_CHANNELS"); ok {
+		cfg.IgnoredChannels = strings.Split(v, ",")
+	}
+	if v, ok := os.LookupEnv("BOTC_NIGHT_MOVES_FROM_DAY_CATEGORY_ONLY"); ok {
+		if d, err := strconv.ParseBool(v); err != nil {
+			return nil, err
+		} else {
+			cfg.NightMovesFromDayCategoryOnly = d
+		}
+	}
+
+	if v, ok := os.LookupEnv("BOTC_COTTAGES_IN_NIGHT_ORDER"); ok {
+		if d, err := strconv.ParseBool(v); err != nil {
+			return nil, err
+		} else {
+			cfg.CottagesInNightOrder = d
+		}
+	}
+
+	guilds, err := guildConfigsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Guilds = guilds
 
 	return cfg, nil
 }
@@ -96,11 +531,11 @@ func (c *Config) Validate() error {
 	switch {
 	case len(c.Tokens) == 0:
 		return fmt.Errorf("no discord bot tokens specified")
-	case c.NightPhaseCategory == "":
+	case c.NightPhaseCategory == "" && c.NightPhaseCategoryID == "":
 		return fmt.Errorf("night phase voice channel category is empty")
-	case c.DayPhaseCategory == "":
+	case c.DayPhaseCategory == "" && c.DayPhaseCategoryID == "":
 		return fmt.Errorf("day phase voice channel category is empty")
-	case c.TownSquare == "":
+	case c.TownSquare == "" && c.TownSquareID == "":
 		return fmt.Errorf("town square voice channel name is empty")
 	case c.MovementDeadlineSeconds <= 0:
 		return fmt.Errorf("invalid deadline %d (must be >0) for movement operations", c.MovementDeadlineSeconds)
@@ -108,6 +543,27 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid deadline %d (must be >0) for requests", c.PerRequestSeconds)
 	case c.MaxConcurrentRequests <= 0:
 		return fmt.Errorf("invalid max number of concurrent requests %d (must be >0) ", c.MaxConcurrentRequests)
+	case c.MaxCottages < 0:
+		return fmt.Errorf("invalid max number of cottages %d (must be >=0)", c.MaxCottages)
+	case c.CottageMute != "" && c.CottageMute != cottageMuteOn && c.CottageMute != cottageMuteOff:
+		return fmt.Errorf("invalid cottage mute %q (must be empty, %q or %q)", c.CottageMute, cottageMuteOn, cottageMuteOff)
+	case c.WhisperSeconds < 0:
+		return fmt.Errorf("invalid whisper time limit %d (must be >=0)", c.WhisperSeconds)
+	case c.MaxWhisperParticipants < 0:
+		return fmt.Errorf("invalid max number of whisper participants %d (must be >=0)", c.MaxWhisperParticipants)
+	case c.MaxWhispersPerDay < 0:
+		return fmt.Errorf("invalid max number of whispers per day %d (must be >=0)", c.MaxWhispersPerDay)
+	}
+	for _, seconds := range c.TimerWarnings {
+		if seconds <= 0 {
+			return fmt.Errorf("invalid timer warning %d (must be >0)", seconds)
+		}
+	}
+
+	for guildID := range c.Guilds {
+		if err := c.ForGuild(guildID).Validate(); err != nil {
+			return fmt.Errorf("guild %s: %w", guildID, err)
+		}
 	}
 
 	return nil
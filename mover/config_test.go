@@ -26,6 +26,95 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			desc: "IDs instead of names",
+			cfg: &Config{
+				Tokens:                  []string{"a", "b", "c"},
+				NightPhaseCategoryID:    "1",
+				DayPhaseCategoryID:      "2",
+				TownSquareID:            "3",
+				StoryTellerRoleID:       "4",
+				MovementDeadlineSeconds: 15,
+				PerRequestSeconds:       5,
+				MaxConcurrentRequests:   1,
+			},
+			wantErr: false,
+		},
+		{
+			desc: "missing town square",
+			cfg: &Config{
+				Tokens:                  []string{"a", "b", "c"},
+				NightPhaseCategory:      "nightphase",
+				DayPhaseCategory:        "dayphase",
+				StoryTellerRole:         "storyteller",
+				MovementDeadlineSeconds: 15,
+				PerRequestSeconds:       5,
+				MaxConcurrentRequests:   1,
+			},
+			wantErr: true,
+		},
+		{
+			desc: "invalid guild override",
+			cfg: &Config{
+				Tokens:                  []string{"a", "b", "c"},
+				NightPhaseCategory:      "nightphase",
+				DayPhaseCategory:        "dayphase",
+				TownSquare:              "townsquare",
+				StoryTellerRole:         "storyteller",
+				MovementDeadlineSeconds: 15,
+				PerRequestSeconds:       5,
+				MaxConcurrentRequests:   1,
+				Guilds: map[string]*GuildConfig{
+					"1234": {MovementDeadlineSeconds: -1},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			desc: "negative whisper time limit",
+			cfg: &Config{
+				Tokens:                  []string{"a", "b", "c"},
+				NightPhaseCategory:      "nightphase",
+				DayPhaseCategory:        "dayphase",
+				TownSquare:              "townsquare",
+				StoryTellerRole:         "storyteller",
+				MovementDeadlineSeconds: 15,
+				PerRequestSeconds:       5,
+				MaxConcurrentRequests:   1,
+				WhisperSeconds:          -1,
+			},
+			wantErr: true,
+		},
+		{
+			desc: "invalid cottage mute",
+			cfg: &Config{
+				Tokens:                  []string{"a", "b", "c"},
+				NightPhaseCategory:      "nightphase",
+				DayPhaseCategory:        "dayphase",
+				TownSquare:              "townsquare",
+				StoryTellerRole:         "storyteller",
+				MovementDeadlineSeconds: 15,
+				PerRequestSeconds:       5,
+				MaxConcurrentRequests:   1,
+				CottageMute:             "deafen",
+			},
+			wantErr: true,
+		},
+		{
+			desc: "invalid timer warning",
+			cfg: &Config{
+				Tokens:                  []string{"a", "b", "c"},
+				NightPhaseCategory:      "nightphase",
+				DayPhaseCategory:        "dayphase",
+				TownSquare:              "townsquare",
+				StoryTellerRole:         "storyteller",
+				MovementDeadlineSeconds: 15,
+				PerRequestSeconds:       5,
+				MaxConcurrentRequests:   1,
+				TimerWarnings:           []int{60, 0},
+			},
+			wantErr: true,
+		},
 		{
 			desc: "missing tokens",
 			cfg: &Config{
@@ -66,9 +155,23 @@ func TestConfigFromEnv(t *testing.T) {
 	t.Setenv("BOTC_DAY_PHASE_CATEGORY", "dayphase")
 	t.Setenv("BOTC_TOWN_SQUARE", "townsquare")
 	t.Setenv("BOTC_STORY_TELLER_ROLE", "storyteller")
+	t.Setenv("BOTC_NIGHT_PHASE_CATEGORY_ID", "1")
+	t.Setenv("BOTC_DAY_PHASE_CATEGORY_ID", "2")
+	t.Setenv("BOTC_TOWN_SQUARE_ID", "3")
+	t.Setenv("BOTC_STORY_TELLER_ROLE_ID", "4")
 	t.Setenv("BOTC_MOVEMENT_DEADLINE_SECONDS", "15")
 	t.Setenv("BOTC_PER_REQUEST_SECONDS", "5")
 	t.Setenv("BOTC_MAX_CONCURRENT_REQUESTS", "3")
+	t.Setenv("BOTC_SPECTATOR_ROLE", "spectator")
+	t.Setenv("BOTC_IGNORED_CHANNELS", "AFK,Music")
+	t.Setenv("BOTC_NIGHT_MOVES_FROM_DAY_CATEGORY_ONLY", "true")
+	t.Setenv("BOTC_MAX_WHISPER_PARTICIPANTS", "3")
+	t.Setenv("BOTC_AUTO_CREATE_COTTAGES", "true")
+	t.Setenv("BOTC_COTTAGE_NAME_TEMPLATE", "Cottage #{n}")
+	t.Setenv("BOTC_COTTAGE_MUTE", "mute")
+	t.Setenv("BOTC_COTTAGES_IN_NIGHT_ORDER", "true")
+	t.Setenv("BOTC_TIMER_WARNINGS", "30, 5")
+	t.Setenv("BOTC_DEAD_NICKNAME_PREFIX", "💀 ")
 
 	got, err := ConfigFromEnv()
 	if err != nil {
@@ -81,12 +184,169 @@ func TestConfigFromEnv(t *testing.T) {
 		DayPhaseCategory:        "dayphase",
 		TownSquare:              "townsquare",
 		StoryTellerRole:         "storyteller",
+		NightPhaseCategoryID:    "1",
+		DayPhaseCategoryID:      "2",
+		TownSquareID:            "3",
+		StoryTellerRoleID:       "4",
 		MovementDeadlineSeconds: 15,
 		PerRequestSeconds:       5,
 		MaxConcurrentRequests:   3,
+		AutoCreateCottages:      true,
+		CottageNameTemplate:     "Cottage #{n}",
+		CottageMute:             "mute",
+		CottagesInNightOrder:    true,
+		WhisperSeconds:          180,
+		MaxWhisperParticipants:  3,
+		TimerWarnings:           []int{30, 5},
+		DeadNicknamePrefix:      "💀 ",
+
+		SpectatorRole:                 "spectator",
+		IgnoredChannels:               []string{"AFK", "Music"},
+		NightMovesFromDayCategoryOnly: true,
 	}
 
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Fatalf("Config loaded from environment variables mismatch (-want, +got):%s\n", diff)
 	}
 }
+
+func TestForGuild(t *testing.T) {
+	maxCottages := 12
+	maxWhispersPerDay := 0
+	cottageMute := ""
+	cfg := &Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "nightphase",
+		DayPhaseCategory:        "dayphase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+		MaxWhispersPerDay:       3,
+		CottageMute:             cottageMuteOn,
+		Guilds: map[string]*GuildConfig{
+			"1234": {
+				NightPhaseCategory:      "cottages",
+				StoryTellerRoleID:       "5678",
+				MovementDeadlineSeconds: 30,
+				MaxCottages:             &maxCottages,
+				// Zero and empty overrides turn the global settings off.
+				MaxWhispersPerDay: &maxWhispersPerDay,
+				CottageMute:       &cottageMute,
+			},
+		},
+	}
+
+	want := &Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "cottages",
+		DayPhaseCategory:        "dayphase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		StoryTellerRoleID:       "5678",
+		MovementDeadlineSeconds: 30,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+		MaxCottages:             12,
+	}
+	if diff := cmp.Diff(want, cfg.ForGuild("1234")); diff != "" {
+		t.Errorf("Config for guild 1234 mismatch (-want, +got):%s\n", diff)
+	}
+
+	// Guilds without overrides use the global defaults.
+	want = &Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "nightphase",
+		DayPhaseCategory:        "dayphase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+		MaxWhispersPerDay:       3,
+		CottageMute:             cottageMuteOn,
+	}
+	if diff := cmp.Diff(want, cfg.ForGuild("other")); diff != "" {
+		t.Errorf("Config for guild without overrides mismatch (-want, +got):%s\n", diff)
+	}
+
+	// A guild without overrides in JSON ("1234": null) uses the global defaults as well.
+	cfg.Guilds["null"] = nil
+	if diff := cmp.Diff(want, cfg.ForGuild("null")); diff != "" {
+		t.Errorf("Config for guild with null overrides mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestForGuildNameOverridesGlobalID(t *testing.T) {
+	cfg := &Config{
+		TownSquare:           "townsquare",
+		TownSquareID:         "1111",
+		NightPhaseCategoryID: "2222",
+		Guilds: map[string]*GuildConfig{
+			"1234": {TownSquare: "Town Square", NightPhaseCategory: "Cottages"},
+		},
+	}
+
+	got := cfg.ForGuild("1234")
+	want := &Config{TownSquare: "Town Square", NightPhaseCategory: "Cottages"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Config for guild 1234 mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestGuildConfigsFromEnv(t *testing.T) {
+	t.Setenv("BOTC_TOKENS", "a")
+	t.Setenv("BOTC_GUILD_1234_NIGHT_PHASE_CATEGORY", "cottages")
+	t.Setenv("BOTC_GUILD_1234_STORY_TELLER_ROLE", "ST")
+	t.Setenv("BOTC_GUILD_1234_MAX_COTTAGES", "12")
+	t.Setenv("BOTC_GUILD_1234_WHISPER_SECONDS", "60")
+	t.Setenv("BOTC_GUILD_1234_MAX_WHISPERS_PER_DAY", "0")
+	t.Setenv("BOTC_GUILD_1234_DEAD_NICKNAME_PREFIX", "")
+	t.Setenv("BOTC_GUILD_1234_COTTAGES_IN_NIGHT_ORDER", "false")
+	t.Setenv("BOTC_GUILD_5678_TOWN_SQUARE_ID", "42")
+	t.Setenv("BOTC_GUILD_5678_PER_REQUEST_SECONDS", "10")
+	t.Setenv("BOTC_GUILD_5678_IGNORED_CHANNELS", "AFK,Music")
+	t.Setenv("BOTC_GUILD_5678_NIGHT_MOVES_FROM_DAY_CATEGORY_ONLY", "true")
+	t.Setenv("BOTC_GUILD_5678_TIMER_WARNINGS", "")
+
+	got, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Cannot load config from environment variables: %v", err)
+	}
+
+	nightMovesFromDayCategoryOnly := true
+	cottagesInNightOrder := false
+	maxCottages := 12
+	whisperSeconds := 60
+	maxWhispersPerDay := 0
+	deadNicknamePrefix := ""
+	want := map[string]*GuildConfig{
+		"1234": {
+			NightPhaseCategory:   "cottages",
+			StoryTellerRole:      "ST",
+			MaxCottages:          &maxCottages,
+			WhisperSeconds:       &whisperSeconds,
+			CottagesInNightOrder: &cottagesInNightOrder,
+			// Present but zero or empty settings are overrides as well.
+			MaxWhispersPerDay:  &maxWhispersPerDay,
+			DeadNicknamePrefix: &deadNicknamePrefix,
+		},
+		"5678": {
+			TownSquareID:                  "42",
+			PerRequestSeconds:             10,
+			IgnoredChannels:               []string{"AFK", "Music"},
+			NightMovesFromDayCategoryOnly: &nightMovesFromDayCategoryOnly,
+			// Timer warnings are disabled.
+			TimerWarnings: []int{},
+		},
+	}
+	if diff := cmp.Diff(want, got.Guilds); diff != "" {
+		t.Fatalf("Guild configs loaded from environment variables mismatch (-want, +got):%s\n", diff)
+	}
+
+	t.Setenv("BOTC_GUILD_1234_UNKNOWN", "foo")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Fatal("Expected error for unknown guild setting.")
+	}
+}
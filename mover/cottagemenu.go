@@ -0,0 +1,157 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// selectCottage is the custom ID of the cottage select menu of /buttons.
+const selectCottage = "selectCottage"
+
+const (
+	// maxSelectMenuOptions is the maximum number of options discord allows in a select menu.
+	maxSelectMenuOptions = 25
+	// maxSelectMenuLabel is the maximum length of a select menu option label or description.
+	maxSelectMenuLabel = 100
+)
+
+// cottageOccupants returns the display names of all members in each cottage, keyed by cottage ID.
+// Story tellers are not occupants. The given moves take precedence over the voice state, so that
+// the occupants can be rendered before discord reports the moves.
+func cottageOccupants(vs *discordVoiceState, moves map[string]string) map[string][]string {
+	isCottage := make(map[string]bool)
+	for _, cottage := range vs.cottages {
+		isCottage[cottage.ID] = true
+	}
+
+	occupants := make(map[string][]string)
+	for _, member := range vs.members {
+		if vs.isStoryTeller(member) {
+			continue
+		}
+		channelID, ok := moves[member.User.ID]
+		if !ok {
+			userVoiceState := vs.userToVoiceState[member.User.ID]
+			if userVoiceState == nil {
+				continue
+			}
+			channelID = userVoiceState.ChannelID
+		}
+		if isCottage[channelID] {
+			occupants[channelID] = append(occupants[channelID], memberName(member))
+		}
+	}
+	return occupants
+}
+
+// cottageMenu returns the select menu listing every occupied cottage in cottage order. The menu is
+// disabled if vs is nil or nobody is in a cottage.
+func cottageMenu(vs *discordVoiceState, moves map[string]string) discordgo.SelectMenu {
+	menu := discordgo.SelectMenu{
+		CustomID:    selectCottage,
+		Placeholder: "🏠 Visit a cottage",
+	}
+	if vs != nil {
+		occupants := cottageOccupants(vs, moves)
+		for _, cottage := range vs.cottages {
+			names, ok := occupants[cottage.ID]
+			if !ok {
+				continue
+			}
+			if len(menu.Options) == maxSelectMenuOptions {
+				break
+			}
+			menu.Options = append(menu.Options, discordgo.SelectMenuOption{
+				Label:       truncate(strings.Join(names, ", "), maxSelectMenuLabel),
+				Value:       cottage.ID,
+				Description: truncate(cottage.Name, maxSelectMenuLabel),
+			})
+		}
+	}
+
+	// Discord rejects select menus without options, even disabled ones.
+	if len(menu.Options) == 0 {
+		menu.Placeholder = "🏠 Nobody is in a cottage"
+		menu.Disabled = true
+		menu.Options = []discordgo.SelectMenuOption{{Label: "Nobody is in a cottage", Value: "none"}}
+	}
+	return menu
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
+
+// visitCottage handles the cottage select menu. The story teller who picked a cottage is moved
+// into it.
+func (b *Bot) visitCottage(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	values := i.MessageComponentData().Values
+	if len(values) == 0 {
+		return fmt.Errorf("no cottage selected")
+	}
+	cottageID := values[0]
+
+	vs, err := b.buildDiscordVoiceState(ctx, s, i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot build voice state: %w", err)
+	}
+	var cottage *discordgo.Channel
+	for _, c := range vs.cottages {
+		if c.ID == cottageID {
+			cottage = c
+			break
+		}
+	}
+	if cottage == nil {
+		return fmt.Errorf("channel %s is not a cottage", cottageID)
+	}
+
+	names := cottageOccupants(vs, nil)[cottageID]
+	if len(names) == 0 {
+		names = []string{"nobody"}
+	}
+	log.Printf("Moving story teller %s to cottage %s in guild %s.", i.Member.User.ID, cottageID, i.GuildID)
+
+	plan := &movementPlan{
+		moves:       map[string]string{i.Member.User.ID: cottageID},
+		guild:       i.GuildID,
+		description: fmt.Sprintf("🏠 Visiting %s: %s", cottage.Name, strings.Join(names, ", ")),
+	}
+	if userVoiceState := vs.userToVoiceState[i.Member.User.ID]; userVoiceState != nil && userVoiceState.ChannelID == cottageID {
+		plan.moves = nil
+		plan.inPlace = map[string]string{i.Member.User.ID: cottageID}
+	}
+	plan.onDone = func(*movementReport) { b.refreshCottageMenu(s, i, vs, nil) }
+
+	_, err = b.dispatchPlan(ctx, s, i, plan)
+	return err
+}
+
+// refreshCottageMenu re-renders the /buttons message whose component was used, so that the
+// cottage select menu lists the current occupants and no longer shows a selection. Does nothing
+// if the interaction did not come from a message.
+func (b *Bot) refreshCottageMenu(s discordSession, i *discordgo.InteractionCreate, vs *discordVoiceState, moves map[string]string) {
+	if i.Message == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(b.cfg.ForGuild(i.GuildID).PerRequestSeconds)*time.Second)
+	defer cancel()
+
+	components := buttonComponents(cottageMenu(vs, moves))
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         i.Message.ID,
+		Channel:    i.Message.ChannelID,
+		Components: &components,
+	}, discordgo.WithContext(ctx)); err != nil {
+		log.Printf("Cannot refresh cottage menu in guild %s: %v", i.GuildID, err)
+	}
+}
@@ -0,0 +1,141 @@
+package mover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func cottageMenuConfig() *Config {
+	return &Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	}
+}
+
+func TestCottageMenu(t *testing.T) {
+	b, _ := newTestBot(cottageMenuConfig())
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user1", ChannelID: "cottage2"},
+			{UserID: "user2", ChannelID: "cottage2"},
+			{UserID: "user3", ChannelID: "townsquare"},
+			{UserID: "storyteller", ChannelID: "cottage1"},
+		},
+	}
+	vs, err := b.buildDiscordVoiceState(context.Background(), d, "guild")
+	if err != nil {
+		t.Fatalf("Cannot build voice state: %v", err)
+	}
+
+	// Story tellers do not occupy cottages, pending moves take precedence over the voice state.
+	menu := cottageMenu(vs, map[string]string{"user3": "cottage4"})
+	want := []discordgo.SelectMenuOption{
+		{Label: "User 1, User 2", Value: "cottage2", Description: "cottage2"},
+		{Label: "User 3", Value: "cottage4", Description: "cottage4"},
+	}
+	if diff := cmp.Diff(want, menu.Options); diff != "" {
+		t.Errorf("Options mismatch (-want, +got):%s\n", diff)
+	}
+	if menu.Disabled {
+		t.Error("Expected the menu to be enabled.")
+	}
+
+	// Everyone returns to Town Square.
+	menu = cottageMenu(vs, map[string]string{"user1": "townsquare", "user2": "townsquare"})
+	if !menu.Disabled || len(menu.Options) != 1 {
+		t.Errorf("Expected a disabled menu with a single placeholder option, got %#v", menu)
+	}
+	if menu := cottageMenu(nil, nil); !menu.Disabled {
+		t.Error("Expected the menu to be disabled without voice state.")
+	}
+}
+
+func TestVisitCottage(t *testing.T) {
+	b, plans := newTestBot(cottageMenuConfig())
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user1", ChannelID: "cottage1"},
+			{UserID: "user2", ChannelID: "cottage3"},
+			{UserID: "storyteller", ChannelID: "cottage1"},
+		},
+	}
+	selectInteraction := func(cottage string) *discordgo.InteractionCreate {
+		return &discordgo.InteractionCreate{
+			Interaction: &discordgo.Interaction{
+				Type:    discordgo.InteractionMessageComponent,
+				GuildID: "guild",
+				Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}},
+				Message: &discordgo.Message{ID: "buttons", ChannelID: "text"},
+				Data:    discordgo.MessageComponentInteractionData{CustomID: selectCottage, Values: []string{cottage}},
+			},
+		}
+	}
+	ctx := context.Background()
+
+	if err := b.visitCottage(ctx, d, selectInteraction("townsquare")); err == nil {
+		t.Error("Expected error when visiting Town Square, got nil")
+	}
+
+	if err := b.visitCottage(ctx, d, selectInteraction("cottage3")); err != nil {
+		t.Fatalf("Cannot visit cottage: %v", err)
+	}
+	var plan *movementPlan
+	select {
+	case plan = <-plans:
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	if diff := cmp.Diff(map[string]string{"storyteller": "cottage3"}, plan.moves); diff != "" {
+		t.Errorf("Moves mismatch (-want, +got):%s\n", diff)
+	}
+	if want := "🏠 Visiting cottage3: User 2"; plan.description != want {
+		t.Errorf("Unexpected description %q, want %q", plan.description, want)
+	}
+
+	// The menu of the /buttons message is refreshed once the story teller arrived.
+	plan.onDone(&movementReport{})
+	if len(d.messageEdits) != 1 {
+		t.Fatalf("Expected the /buttons message to be refreshed, got %d edits", len(d.messageEdits))
+	}
+	edit := d.messageEdits[0]
+	if edit.ID != "buttons" || edit.Channel != "text" {
+		t.Errorf("Unexpected message %s in channel %s refreshed", edit.ID, edit.Channel)
+	}
+	components := *edit.Components
+	menu := components[len(components)-1].(discordgo.ActionsRow).Components[0].(discordgo.SelectMenu)
+	if len(menu.Options) != 2 {
+		t.Errorf("Expected 2 occupied cottages, got %#v", menu.Options)
+	}
+
+	// A story teller already in the cottage stays in place.
+	if err := b.visitCottage(ctx, d, selectInteraction("cottage1")); err != nil {
+		t.Fatalf("Cannot visit cottage: %v", err)
+	}
+	select {
+	case plan = <-plans:
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	if len(plan.moves) != 0 {
+		t.Errorf("Expected no moves, got %v", plan.moves)
+	}
+	if diff := cmp.Diff(map[string]string{"storyteller": "cottage1"}, plan.inPlace); diff != "" {
+		t.Errorf("In place mismatch (-want, +got):%s\n", diff)
+	}
+}
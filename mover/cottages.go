@@ -0,0 +1,179 @@
+package mover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// defaultCottageNameTemplate is used to name created cottages if no template is configured.
+const defaultCottageNameTemplate = "Cottage {n}"
+
+// notEnoughCottagesError is returned if there are more players than free cottages.
+type notEnoughCottagesError struct {
+	needed    int
+	available int
+}
+
+func (e *notEnoughCottagesError) Error() string {
+	return fmt.Sprintf("not enough cottages available, need %d user movements but only have %d empty cottages", e.needed, e.available)
+}
+
+// missing returns the number of cottages that are missing.
+func (e *notEnoughCottagesError) missing() int {
+	return e.needed - e.available
+}
+
+// cottageName returns the name of the n-th cottage according to the template. The template
+// contains {n} as placeholder for the cottage number.
+func cottageName(template string, n int) string {
+	if template == "" {
+		template = defaultCottageNameTemplate
+	}
+	return strings.ReplaceAll(template, "{n}", strconv.Itoa(n))
+}
+
+// createCottages creates the given number of missing cottages for the night phase. All created
+// cottages are remembered in the game state so that they can be cleaned up later.
+func (b *Bot) createCottages(ctx context.Context, s discordSession, guildID string, count int) error {
+	cfg := b.cfg.ForGuild(guildID)
+	channels, err := s.GuildChannels(guildID, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("cannot list guild channels: %w", err)
+	}
+	nightCategory := findChannel(channels, cfg.NightPhaseCategoryID, cfg.NightPhaseCategory)
+	if nightCategory == nil {
+		return fmt.Errorf("cannot find night category %s", configRef(cfg.NightPhaseCategoryID, cfg.NightPhaseCategory))
+	}
+	if existing := len(cottagesOf(channels, nightCategory)); cfg.MaxCottages > 0 && existing+count > cfg.MaxCottages {
+		return fmt.Errorf("cannot create %d cottage(s), at most %d cottages are used", count, cfg.MaxCottages)
+	}
+
+	log.Printf("Creating %d cottage(s) for guild %s.", count, guildID)
+	created, err := createCottageChannels(ctx, s, guildID, cfg.CottageNameTemplate, channels, nightCategory, count)
+	if updateErr := b.store.Update(guildID, func(state *GameState) error {
+		state.CreatedCottages = append(state.CreatedCottages, created...)
+		return nil
+	}); updateErr != nil {
+		return fmt.Errorf("cannot remember created cottages: %w", updateErr)
+	}
+	return err
+}
+
+// cottagesOf returns all cottages of the night category, sorted by their position.
+func cottagesOf(channels []*discordgo.Channel, nightCategory *discordgo.Channel) []*discordgo.Channel {
+	var cottages []*discordgo.Channel
+	for _, channel := range channels {
+		if channel.Type == discordgo.ChannelTypeGuildVoice && channel.ParentID == nightCategory.ID {
+			cottages = append(cottages, channel)
+		}
+	}
+	slices.SortFunc(cottages, func(a, b *discordgo.Channel) int {
+		return a.Position - b.Position
+	})
+	return cottages
+}
+
+// createCottageChannels creates the given number of cottages in the night category and returns
+// their IDs, even if not all cottages could be created. The new cottages copy the permission
+// overwrites of the first existing cottage so that they are just as private.
+func createCottageChannels(ctx context.Context, s discordSession, guildID, nameTemplate string, channels []*discordgo.Channel, nightCategory *discordgo.Channel, count int) ([]string, error) {
+	cottages := cottagesOf(channels, nightCategory)
+	names := make(map[string]bool)
+	for _, channel := range channels {
+		names[channel.Name] = true
+	}
+
+	// Copy the permissions of an existing cottage, or those of the night category if there are no
+	// cottages yet.
+	template := nightCategory
+	position := 0
+	if len(cottages) > 0 {
+		template = cottages[0]
+		position = cottages[len(cottages)-1].Position
+	}
+
+	var created []string
+	n := len(cottages)
+	for len(created) < count {
+		name := cottageName(nameTemplate, n+1)
+		for names[name] {
+			n++
+			name = cottageName(nameTemplate, n+1)
+		}
+		n++
+		position++
+
+		cottage, err := s.GuildChannelCreateComplex(guildID, discordgo.GuildChannelCreateData{
+			Name:                 name,
+			Type:                 discordgo.ChannelTypeGuildVoice,
+			ParentID:             nightCategory.ID,
+			Position:             position,
+			PermissionOverwrites: template.PermissionOverwrites,
+		}, discordgo.WithContext(ctx))
+		if err != nil {
+			return created, fmt.Errorf("cannot create cottage %q: %w", name, err)
+		}
+		names[name] = true
+		created = append(created, cottage.ID)
+	}
+
+	return created, nil
+}
+
+// cleanupCottages deletes all cottages that were created by the bot. Not possible while a game is
+// running, since players may still need the cottages.
+func (b *Bot) cleanupCottages(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	state, err := b.store.Get(i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot load game state: %w", err)
+	}
+	if state.Running {
+		return fmt.Errorf("cannot clean up cottages while a game is running, use `/game end` first")
+	}
+	if len(state.CreatedCottages) == 0 {
+		return respondEphemeral(ctx, s, i, "There are no cottages to clean up.")
+	}
+
+	// Deleting the cottages one by one takes longer than discord waits for the interaction to be
+	// acknowledged.
+	return b.deferInteraction(ctx, s, i, func(s discordSession) error {
+		log.Printf("Deleting %d created cottage(s) of guild %s.", len(state.CreatedCottages), i.GuildID)
+		var deleted []string
+		var deleteErr error
+		for _, cottageID := range state.CreatedCottages {
+			// Cottages that have been deleted by hand are forgotten as well.
+			if _, err := s.ChannelDelete(cottageID, discordgo.WithContext(ctx)); err != nil && !isUnknownChannel(err) {
+				deleteErr = fmt.Errorf("cannot delete cottage %s: %w", cottageID, err)
+				break
+			}
+			deleted = append(deleted, cottageID)
+		}
+
+		if err := b.store.Update(i.GuildID, func(state *GameState) error {
+			state.CreatedCottages = slices.DeleteFunc(state.CreatedCottages, func(cottageID string) bool {
+				return slices.Contains(deleted, cottageID)
+			})
+			return nil
+		}); err != nil {
+			return fmt.Errorf("cannot update game state: %w", err)
+		}
+		if deleteErr != nil {
+			return deleteErr
+		}
+
+		return respondEphemeral(ctx, s, i, fmt.Sprintf("Deleted %d cottage(s).", len(deleted)))
+	})
+}
+
+// isUnknownChannel returns true iff the error was caused by a channel that does not exist.
+func isUnknownChannel(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownChannel
+}
@@ -0,0 +1,162 @@
+package mover
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrepareNightMovesCreatesCottages(t *testing.T) {
+	for _, tc := range []struct {
+		desc        string
+		autoCreate  bool
+		template    string
+		wantErr     bool
+		wantCreated []discordgo.GuildChannelCreateData
+	}{
+		{
+			desc:    "disabled",
+			wantErr: true,
+		},
+		{
+			desc:       "default template",
+			autoCreate: true,
+			wantCreated: []discordgo.GuildChannelCreateData{
+				{Name: "Cottage 4", Type: discordgo.ChannelTypeGuildVoice, ParentID: "night phase", Position: 6, PermissionOverwrites: privateCottage},
+				{Name: "Cottage 5", Type: discordgo.ChannelTypeGuildVoice, ParentID: "night phase", Position: 7, PermissionOverwrites: privateCottage},
+			},
+		},
+		{
+			desc:       "custom template",
+			autoCreate: true,
+			// Existing names are skipped.
+			template: "cottage{n}",
+			wantCreated: []discordgo.GuildChannelCreateData{
+				{Name: "cottage6", Type: discordgo.ChannelTypeGuildVoice, ParentID: "night phase", Position: 6, PermissionOverwrites: privateCottage},
+				{Name: "cottage7", Type: discordgo.ChannelTypeGuildVoice, ParentID: "night phase", Position: 7, PermissionOverwrites: privateCottage},
+			},
+		},
+	} {
+		b, plans := newTestBot(&Config{
+			Tokens:                  []string{"a", "b", "c"},
+			NightPhaseCategory:      "night phase",
+			DayPhaseCategory:        "day phase",
+			TownSquare:              "townsquare",
+			StoryTellerRole:         "storyteller",
+			MovementDeadlineSeconds: 15,
+			PerRequestSeconds:       5,
+			MaxConcurrentRequests:   3,
+			AutoCreateCottages:      tc.autoCreate,
+			CottageNameTemplate:     tc.template,
+		})
+
+		// Only 3 cottages are left for 5 moves.
+		d := &fakeDiscordSession{
+			id:              "guild",
+			deletedChannels: []string{"cottage1", "cottage2"},
+		}
+		i := &discordgo.InteractionCreate{
+			Interaction: &discordgo.Interaction{
+				GuildID: "guild",
+				Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}},
+			},
+		}
+
+		err := b.prepareNightMoves(context.Background(), d, i)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%s: prepareNightMoves() returned unexpected error %v, want error: %t", tc.desc, err, tc.wantErr)
+		}
+		if diff := cmp.Diff(tc.wantCreated, d.createdChannels); diff != "" {
+			t.Errorf("%s: Created cottages mismatch (-want, +got):%s\n", tc.desc, diff)
+		}
+		if tc.wantErr {
+			continue
+		}
+
+		select {
+		case plan := <-plans:
+			if len(plan.moves) != 5 {
+				t.Errorf("%s: Expected 5 moves, got %v", tc.desc, plan.moves)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: Expected to receive plan, got nothing.", tc.desc)
+		}
+		b.plans.Wait()
+
+		state, err := b.store.Get("guild")
+		if err != nil {
+			t.Fatalf("Cannot load game state: %v", err)
+		}
+		if diff := cmp.Diff([]string{"created1", "created2"}, state.CreatedCottages); diff != "" {
+			t.Errorf("%s: Remembered cottages mismatch (-want, +got):%s\n", tc.desc, diff)
+		}
+
+		// The interaction is acknowledged once, before the cottages are created.
+		if len(d.responses) != 1 || d.responses[0].Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+			t.Errorf("%s: Expected a single deferred response, got %v", tc.desc, d.responses)
+		}
+	}
+}
+
+func TestCleanupCottages(t *testing.T) {
+	b, _ := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	d := &fakeDiscordSession{id: "guild"}
+	ctx := context.Background()
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+		},
+	}
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.CreatedCottages = []string{"created1", "created2"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	if err := b.cleanupCottages(ctx, d, i); err == nil {
+		t.Error("Expected error while the game is running, got nil")
+	}
+
+	// Created cottages survive the end of the game.
+	if err := b.endGame(ctx, d, i); err != nil {
+		t.Fatalf("Cannot end game: %v", err)
+	}
+	d.responses = nil
+	d.edits = nil
+	if err := b.cleanupCottages(ctx, d, i); err != nil {
+		t.Fatalf("Cannot clean up cottages: %v", err)
+	}
+	if diff := cmp.Diff([]string{"created1", "created2"}, d.deletedChannels); diff != "" {
+		t.Errorf("Deleted cottages mismatch (-want, +got):%s\n", diff)
+	}
+	// The interaction is acknowledged before the cottages are deleted.
+	if len(d.responses) != 1 || d.responses[0].Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		t.Errorf("Expected a single deferred response, got %v", d.responses)
+	}
+	if len(d.edits) != 1 || !strings.Contains(*d.edits[0].Content, "Deleted 2 cottage(s)") {
+		t.Errorf("Expected the response to report the deleted cottages, got %v", d.edits)
+	}
+
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if len(state.CreatedCottages) != 0 {
+		t.Errorf("Expected all cottages to be forgotten, got %v", state.CreatedCottages)
+	}
+}
@@ -0,0 +1,137 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// Names of the user context menu commands to mark players dead or alive.
+const (
+	userCommandKill   = "Mark Dead"
+	userCommandRevive = "Mark Alive"
+)
+
+// maxNicknameLength is the maximum length of a discord nickname.
+const maxNicknameLength = 32
+
+// commandTarget returns the user ID of the player targeted by a slash command with a single user
+// option or by a user context menu command.
+func commandTarget(data discordgo.ApplicationCommandInteractionData) (string, error) {
+	if data.CommandType == discordgo.UserApplicationCommand {
+		return data.TargetID, nil
+	}
+	if len(data.Options) == 0 {
+		return "", fmt.Errorf("missing player")
+	}
+	return data.Options[0].UserValue(nil).ID, nil
+}
+
+// setAlive handles /kill, /revive and their context menu commands. The player is marked dead or
+// alive in the game state. If DeadNicknamePrefix is configured, dead players get the prefix in
+// front of their nickname, which is removed again on revive.
+func (b *Bot) setAlive(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, alive bool) error {
+	user, err := commandTarget(i.ApplicationCommandData())
+	if err != nil {
+		return err
+	}
+
+	var original string
+	var hasOriginal bool
+	if err := b.store.Update(i.GuildID, func(state *GameState) error {
+		switch {
+		case !state.Running:
+			return fmt.Errorf("no game is running")
+		case !slices.Contains(state.Players, user):
+			return fmt.Errorf("<@%s> is not seated in the game", user)
+		case alive && state.isAlive(user):
+			return fmt.Errorf("<@%s> is alive already", user)
+		case !alive && !state.isAlive(user):
+			return fmt.Errorf("<@%s> is dead already", user)
+		}
+		if alive {
+			// A revived player gets a new ghost vote once they die again.
+			state.Dead = slices.DeleteFunc(state.Dead, func(dead string) bool { return dead == user })
+			state.GhostVotesUsed = slices.DeleteFunc(state.GhostVotesUsed, func(dead string) bool { return dead == user })
+		} else {
+			state.Dead = append(state.Dead, user)
+		}
+		original, hasOriginal = state.Nicknames[user]
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("<@%s> is now dead.", user)
+	if alive {
+		content = fmt.Sprintf("<@%s> is alive again.", user)
+	}
+	log.Printf("Marked %s as alive: %t in guild %s.", user, alive, i.GuildID)
+
+	prefix := b.cfg.ForGuild(i.GuildID).DeadNicknamePrefix
+	var nicknameErr error
+	switch {
+	case alive && hasOriginal:
+		nicknameErr = b.restoreNicknames(ctx, s, i.GuildID, map[string]string{user: original})
+	case !alive && prefix != "" && !hasOriginal:
+		nicknameErr = b.markNickname(ctx, s, i.GuildID, user, prefix)
+	}
+	if nicknameErr != nil {
+		log.Printf("Cannot change nickname of %s in guild %s: %v", user, i.GuildID, nicknameErr)
+		content += fmt.Sprintf(" Cannot change their nickname: %v", nicknameErr)
+	}
+
+	return respondEphemeral(ctx, s, i, content)
+}
+
+// markNickname puts the prefix in front of the member's nickname and remembers the original
+// nickname in the game state.
+func (b *Bot) markNickname(ctx context.Context, s discordSession, guildID, user, prefix string) error {
+	member, err := s.GuildMember(guildID, user, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("cannot fetch member: %w", err)
+	}
+
+	nickname := []rune(prefix + memberName(member))
+	if len(nickname) > maxNicknameLength {
+		nickname = nickname[:maxNicknameLength]
+	}
+	if err := s.GuildMemberNickname(guildID, user, string(nickname), discordgo.WithContext(ctx)); err != nil {
+		return err
+	}
+
+	return b.store.Update(guildID, func(state *GameState) error {
+		if state.Nicknames == nil {
+			state.Nicknames = make(map[string]string)
+		}
+		state.Nicknames[user] = member.Nick
+		return nil
+	})
+}
+
+// restoreNicknames restores the original nicknames of the given members and forgets them in the
+// game state. Returns the last error, if any.
+func (b *Bot) restoreNicknames(ctx context.Context, s discordSession, guildID string, nicknames map[string]string) error {
+	var restored []string
+	var restoreErr error
+	for user, nickname := range nicknames {
+		if err := s.GuildMemberNickname(guildID, user, nickname, discordgo.WithContext(ctx)); err != nil {
+			restoreErr = fmt.Errorf("cannot restore nickname of <@%s>: %w", user, err)
+			continue
+		}
+		restored = append(restored, user)
+	}
+
+	if err := b.store.Update(guildID, func(state *GameState) error {
+		for _, user := range restored {
+			delete(state.Nicknames, user)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("cannot update game state: %w", err)
+	}
+	return restoreErr
+}
@@ -0,0 +1,158 @@
+package mover
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func killInteraction(command, user string) *discordgo.InteractionCreate {
+	data := discordgo.ApplicationCommandInteractionData{Name: command}
+	switch command {
+	case userCommandKill, userCommandRevive:
+		data.CommandType = discordgo.UserApplicationCommand
+		data.TargetID = user
+	default:
+		data.Options = []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "player", Type: discordgo.ApplicationCommandOptionUser, Value: user},
+		}
+	}
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}},
+			Data:    data,
+		},
+	}
+}
+
+func TestSetAlive(t *testing.T) {
+	b, _ := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+		DeadNicknamePrefix:      "💀 ",
+	})
+	d := &fakeDiscordSession{id: "guild"}
+	ctx := context.Background()
+
+	if err := b.setAlive(ctx, d, killInteraction(slashCommandKill, "user1"), false); err == nil {
+		t.Error("Expected error without a running game, got nil")
+	}
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"user1", "user2", "user3"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	if err := b.setAlive(ctx, d, killInteraction(slashCommandKill, "user1"), false); err != nil {
+		t.Fatalf("Cannot kill user1: %v", err)
+	}
+	if err := b.setAlive(ctx, d, killInteraction(userCommandKill, "user2"), false); err != nil {
+		t.Fatalf("Cannot kill user2: %v", err)
+	}
+	for _, tc := range []struct {
+		desc    string
+		command string
+		user    string
+		alive   bool
+	}{
+		{"already dead", slashCommandKill, "user1", false},
+		{"already alive", slashCommandRevive, "user3", true},
+		{"not seated", slashCommandKill, "storyteller", false},
+	} {
+		if err := b.setAlive(ctx, d, killInteraction(tc.command, tc.user), tc.alive); err == nil {
+			t.Errorf("%s: Expected error, got nil", tc.desc)
+		}
+	}
+
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if diff := cmp.Diff([]string{"user1", "user2"}, state.Dead); diff != "" {
+		t.Errorf("Dead players mismatch (-want, +got):%s\n", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"user1": "💀 User 1", "user2": "💀 User 2"}, d.nicknames); diff != "" {
+		t.Errorf("Nicknames mismatch (-want, +got):%s\n", diff)
+	}
+
+	// Revived players regain their ghost vote and their nickname.
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.GhostVotesUsed = []string{"user1"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+	if err := b.setAlive(ctx, d, killInteraction(userCommandRevive, "user1"), true); err != nil {
+		t.Fatalf("Cannot revive user1: %v", err)
+	}
+	if got := d.responses[len(d.responses)-1].Data.Content; got != "<@user1> is alive again." {
+		t.Errorf("Unexpected response %q", got)
+	}
+	state, err = b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if len(state.GhostVotesUsed) != 0 || !state.isAlive("user1") {
+		t.Errorf("Expected user1 to be alive with a fresh ghost vote, got %+v", state)
+	}
+	if diff := cmp.Diff(map[string]string{"user2": ""}, state.Nicknames); diff != "" {
+		t.Errorf("Remembered nicknames mismatch (-want, +got):%s\n", diff)
+	}
+
+	// The remaining nicknames are restored at the end of the game.
+	if err := b.endGame(ctx, d, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{GuildID: "guild"}}); err != nil {
+		t.Fatalf("Cannot end game: %v", err)
+	}
+	if diff := cmp.Diff(map[string]string{"user1": "", "user2": ""}, d.nicknames); diff != "" {
+		t.Errorf("Nicknames mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestPrepareNightMovesMovesDeadPlayers(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	d := &fakeDiscordSession{id: "guild"}
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"user1", "user2", "user3"}
+		state.Dead = []string{"user2"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{GuildID: "guild"}}
+	if err := b.prepareNightMoves(context.Background(), d, i); err != nil {
+		t.Fatalf("Cannot prepare night moves: %v", err)
+	}
+	select {
+	case plan := <-plans:
+		if got := plan.moves["user2"]; !strings.HasPrefix(got, "cottage") {
+			t.Errorf("Expected dead player to move into a cottage, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+}
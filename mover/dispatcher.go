@@ -0,0 +1,57 @@
+package mover
+
+import (
+	"sync"
+)
+
+// planDispatcher executes movement plans in the background. Plans for different guilds run
+// concurrently, but every guild runs at most one plan at a time.
+type planDispatcher struct {
+	execute func(*movementPlan)
+
+	mu     sync.Mutex
+	active map[string]bool
+	wg     sync.WaitGroup
+}
+
+// newPlanDispatcher creates a new dispatcher that runs every accepted plan with execute.
+func newPlanDispatcher(execute func(*movementPlan)) *planDispatcher {
+	return &planDispatcher{
+		execute: execute,
+		active:  make(map[string]bool),
+	}
+}
+
+// Dispatch starts executing the plan in the background. Returns false if the plan was rejected
+// because another plan for the same guild has not finished yet.
+func (d *planDispatcher) Dispatch(plan *movementPlan) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.active[plan.guild] {
+		return false
+	}
+	d.active[plan.guild] = true
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer d.release(plan.guild)
+		d.execute(plan)
+	}()
+
+	return true
+}
+
+// release marks the guild as idle so that it can accept new plans.
+func (d *planDispatcher) release(guild string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.active, guild)
+}
+
+// Wait blocks until all running plans have finished.
+func (d *planDispatcher) Wait() {
+	d.wg.Wait()
+}
@@ -0,0 +1,48 @@
+package mover
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDispatchOnePlanPerGuild(t *testing.T) {
+	started := make(chan string)
+	release := make(chan bool)
+	d := newPlanDispatcher(func(p *movementPlan) {
+		started <- p.guild
+		<-release
+	})
+
+	if !d.Dispatch(&movementPlan{guild: "guild1"}) {
+		t.Fatal("Expected first plan for guild1 to be accepted.")
+	}
+	<-started
+
+	if d.Dispatch(&movementPlan{guild: "guild1"}) {
+		t.Fatal("Expected second plan for guild1 to be rejected while the first one is running.")
+	}
+
+	// A different guild must not be blocked by guild1.
+	if !d.Dispatch(&movementPlan{guild: "guild2"}) {
+		t.Fatal("Expected plan for guild2 to be accepted while guild1 is busy.")
+	}
+	select {
+	case guild := <-started:
+		if guild != "guild2" {
+			t.Fatalf("Expected plan for guild2 to start, got %s", guild)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Plan for guild2 did not start while guild1 was busy.")
+	}
+
+	release <- true
+	release <- true
+	d.Wait()
+
+	if !d.Dispatch(&movementPlan{guild: "guild1"}) {
+		t.Fatal("Expected guild1 to accept a new plan after the previous one finished.")
+	}
+	<-started
+	release <- true
+	d.Wait()
+}
@@ -0,0 +1,203 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// Subcommands of the /game slash command.
+const (
+	gameCommandStart   = "start"
+	gameCommandEnd     = "end"
+	gameCommandStatus  = "status"
+	gameCommandCleanup = "cleanup"
+	gameCommandScript  = "script"
+)
+
+// onGameCommand handles the /game slash command group.
+func (b *Bot) onGameCommand(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return fmt.Errorf("missing /game subcommand")
+	}
+
+	switch options[0].Name {
+	case gameCommandStart:
+		return b.startGame(ctx, s, i)
+	case gameCommandEnd:
+		return b.endGame(ctx, s, i)
+	case gameCommandStatus:
+		return b.showGameStatus(ctx, s, i)
+	case gameCommandCleanup:
+		return b.cleanupCottages(ctx, s, i)
+	case gameCommandScript:
+		return b.loadScript(ctx, s, i)
+	}
+
+	return fmt.Errorf("unknown /game subcommand: %s", options[0].Name)
+}
+
+// startGame starts a new game with everyone in Town Square (except story tellers) as seated
+// players. All state of the previous game, e.g. cottage assignments, is forgotten. The interaction
+// is acknowledged first, since restoring the nicknames of the previous game may take a while.
+func (b *Bot) startGame(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	log.Printf("Starting new game for guild %s.", i.GuildID)
+
+	return b.deferInteraction(ctx, s, i, func(s discordSession) error {
+		vs, err := b.buildDiscordVoiceState(ctx, s, i.GuildID)
+		if err != nil {
+			return fmt.Errorf("cannot build voice state: %w", err)
+		}
+
+		var players []string
+		for _, member := range vs.members {
+			userVoiceState := vs.userToVoiceState[member.User.ID]
+			if userVoiceState != nil && userVoiceState.ChannelID == vs.townSquare.ID && !vs.isStoryTeller(member) {
+				players = append(players, member.User.ID)
+			}
+		}
+		if len(players) == 0 {
+			return fmt.Errorf("nobody is seated in Town Square")
+		}
+		// Players are seated clockwise in the order they joined Town Square.
+		b.joins.sort(i.GuildID, players)
+		b.restoreAllNicknames(ctx, s, i.GuildID)
+
+		if err := b.store.Update(i.GuildID, func(state *GameState) error {
+			state.reset()
+			state.Running = true
+			state.Game = time.Now().UnixNano()
+			state.Players = players
+			return nil
+		}); err != nil {
+			return fmt.Errorf("cannot store game state: %w", err)
+		}
+
+		return respondEphemeral(ctx, s, i, fmt.Sprintf("Started a new game with %d seated player(s): %s", len(players), mentions(players)))
+	})
+}
+
+// endGame ends the game and forgets all of its state. The interaction is acknowledged first, since
+// restoring the nicknames may take a while.
+func (b *Bot) endGame(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	log.Printf("Ending game for guild %s.", i.GuildID)
+
+	return b.deferInteraction(ctx, s, i, func(s discordSession) error {
+		b.restoreAllNicknames(ctx, s, i.GuildID)
+
+		if err := b.store.Update(i.GuildID, func(state *GameState) error {
+			state.reset()
+			return nil
+		}); err != nil {
+			return fmt.Errorf("cannot delete game state: %w", err)
+		}
+		if t := b.timers.swap(i.GuildID, nil); t != nil {
+			t.cancel()
+		}
+
+		return respondEphemeral(ctx, s, i, "Ended the game, all game state has been cleared.")
+	})
+}
+
+// restoreAllNicknames restores the nicknames of all dead players before the game state is reset.
+// Failures are only logged, the game ends anyway.
+func (b *Bot) restoreAllNicknames(ctx context.Context, s discordSession, guildID string) {
+	state, err := b.store.Get(guildID)
+	if err != nil {
+		log.Printf("Cannot load game state of guild %s: %v", guildID, err)
+		return
+	}
+	if len(state.Nicknames) == 0 {
+		return
+	}
+	if err := b.restoreNicknames(ctx, s, guildID, state.Nicknames); err != nil {
+		log.Printf("Cannot restore nicknames in guild %s: %v", guildID, err)
+	}
+}
+
+// showGameStatus responds with the phase, day number and seated players of the game, and who of
+// them is alive or dead.
+func (b *Bot) showGameStatus(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	state, err := b.store.Get(i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot load game state: %w", err)
+	}
+
+	return respondEphemeral(ctx, s, i, renderGameStatus(state))
+}
+
+// renderGameStatus renders a short human readable summary of the game state.
+func renderGameStatus(state *GameState) string {
+	if !state.Running {
+		return "No game is running. Use `/game start` to seat everyone in Town Square."
+	}
+
+	var lines []string
+	switch state.Phase {
+	case phaseNight:
+		lines = append(lines, fmt.Sprintf("**Night %d**", state.Day+1))
+	case phaseDay:
+		lines = append(lines, fmt.Sprintf("**Day %d**", state.Day))
+	default:
+		lines = append(lines, "**Game has not entered the first night yet**")
+	}
+	if state.Script != nil {
+		lines = append(lines, fmt.Sprintf("Script: %s", state.Script.Name))
+	}
+	lines = append(lines, fmt.Sprintf("Seated players (%d): %s", len(state.Players), mentions(state.Players)))
+	if len(state.Travellers) > 0 {
+		lines = append(lines, fmt.Sprintf("Travellers (%d): %s", len(state.Travellers), mentions(state.Travellers)))
+	}
+
+	var alive, dead []string
+	for _, player := range state.Players {
+		if state.isAlive(player) {
+			alive = append(alive, player)
+		} else {
+			dead = append(dead, player)
+		}
+	}
+	lines = append(lines, fmt.Sprintf("Alive (%d): %s", len(alive), mentions(alive)))
+	if len(dead) > 0 {
+		lines = append(lines, fmt.Sprintf("Dead (%d): %s", len(dead), renderVoters(state, dead)))
+		if len(state.GhostVotesUsed) > 0 {
+			lines = append(lines, fmt.Sprintf("Ghost votes used: %s", mentions(state.GhostVotesUsed)))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// isSeated returns true iff the user takes part in the game. If no game is running, everyone
+// takes part.
+func (g *GameState) isSeated(user string) bool {
+	return !g.Running || slices.Contains(g.Players, user)
+}
+
+// mentions renders user mentions for all users.
+func mentions(users []string) string {
+	var parts []string
+	for _, user := range users {
+		parts = append(parts, fmt.Sprintf("<@%s>", user))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// respondEphemeral responds to the interaction with an ephemeral message. Mentions do not ping
+// anyone.
+func respondEphemeral(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, content string) error {
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:           discordgo.MessageFlagsEphemeral,
+			Content:         content,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}, discordgo.WithContext(ctx))
+}
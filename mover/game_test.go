@@ -0,0 +1,130 @@
+package mover
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGameLifecycle(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+
+	d := &fakeDiscordSession{
+		id: "guild",
+	}
+
+	ctx := context.Background()
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+		},
+	}
+
+	// Only user1 is in Town Square.
+	if err := b.startGame(ctx, d, i); err != nil {
+		t.Fatalf("Cannot start game: %v", err)
+	}
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	// The interaction is acknowledged before the nicknames of the previous game are restored.
+	if len(d.responses) != 1 || d.responses[0].Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		t.Errorf("Expected a single deferred response, got %v", d.responses)
+	}
+	if len(d.edits) != 1 || !strings.Contains(*d.edits[0].Content, "Started a new game with 1 seated player(s)") {
+		t.Errorf("Expected the response to report the new game, got %v", d.edits)
+	}
+	if state.Game == 0 {
+		t.Error("Expected the game to be identified by its start time, got 0")
+	}
+	if diff := cmp.Diff(&GameState{Running: true, Game: state.Game, Players: []string{"user1"}}, state); diff != "" {
+		t.Fatalf("Unexpected game state after start (-want, +got):%s\n", diff)
+	}
+
+	// Only seated players and story tellers are moved.
+	if err := b.prepareNightMoves(ctx, d, i); err != nil {
+		t.Fatalf("Cannot prepare night moves: %v", err)
+	}
+	select {
+	case plan := <-plans:
+		for user := range plan.moves {
+			if user != "user1" && !strings.HasPrefix(user, "storyteller") {
+				t.Errorf("Expected only seated players and story tellers to move, got %s", user)
+			}
+		}
+		if _, ok := plan.moves["user1"]; !ok {
+			t.Errorf("Expected seated player user1 to move, got %#v", plan.moves)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	d.responses = nil
+	if err := b.showGameStatus(ctx, d, i); err != nil {
+		t.Fatalf("Cannot show game status: %v", err)
+	}
+	got := d.responses[0].Data.Content
+	for _, want := range []string{"Night 1", "Seated players (1): <@user1>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected status %q to contain %q", got, want)
+		}
+	}
+
+	if err := b.endGame(ctx, d, i); err != nil {
+		t.Fatalf("Cannot end game: %v", err)
+	}
+	state, err = b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if diff := cmp.Diff(&GameState{}, state); diff != "" {
+		t.Fatalf("Expected empty game state after end (-want, +got):%s\n", diff)
+	}
+}
+
+func TestRenderGameStatus(t *testing.T) {
+	for _, tc := range []struct {
+		state *GameState
+		want  string
+	}{
+		{
+			state: &GameState{},
+			want:  "No game is running",
+		},
+		{
+			state: &GameState{Running: true, Players: []string{"a", "b"}},
+			want:  "Seated players (2): <@a>, <@b>",
+		},
+		{
+			state: &GameState{Running: true, Players: []string{"a", "b", "c"}, Dead: []string{"b"}},
+			want:  "Alive (2): <@a>, <@c>\nDead (1): <@b> 👻",
+		},
+		{
+			state: &GameState{Running: true, Phase: phaseDay, Day: 3},
+			want:  "**Day 3**",
+		},
+		{
+			state: &GameState{Running: true, Phase: phaseNight, Day: 3},
+			want:  "**Night 4**",
+		},
+	} {
+		if got := renderGameStatus(tc.state); !strings.Contains(got, tc.want) {
+			t.Errorf("Expected status %q to contain %q", got, tc.want)
+		}
+	}
+}
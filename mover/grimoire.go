@@ -0,0 +1,233 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// Subcommands of the /grimoire slash command.
+const (
+	grimoireCommandAssign   = "assign"
+	grimoireCommandReminder = "reminder"
+	grimoireCommandShow     = "show"
+)
+
+// Alignments of characters.
+const (
+	alignmentGood = "good"
+	alignmentEvil = "evil"
+)
+
+// defaultAlignment returns the alignment a character starts with. Travellers are good unless the
+// story teller decides otherwise.
+func defaultAlignment(c *character) string {
+	if c.team == teamMinion || c.team == teamDemon {
+		return alignmentEvil
+	}
+	return alignmentGood
+}
+
+// isFirstNight returns true iff the current or upcoming night is the first night of the game.
+func (g *GameState) isFirstNight() bool {
+	return g.Day == 0
+}
+
+// nightOrder returns all living seated players whose character wakes during the current or
+// upcoming night, in night order. Dead players lose their ability.
+func (g *GameState) nightOrder() []string {
+	order := otherNightOrder
+	if g.isFirstNight() {
+		order = firstNightOrder
+	}
+
+	var players []string
+	for _, id := range order {
+		for _, player := range g.Players {
+			if entry := g.Grimoire[player]; entry != nil && entry.Character == id && g.isAlive(player) {
+				players = append(players, player)
+			}
+		}
+	}
+	return players
+}
+
+// cottageOrder returns all seated players in the order they take the cottages at night. Players
+// who wake come first in night order, everyone else follows in seat order.
+func (g *GameState) cottageOrder() []string {
+	players := g.nightOrder()
+	for _, player := range g.Players {
+		if !slices.Contains(players, player) {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+// grimoireEntry returns the grimoire entry of the player, which is created if it does not exist.
+func (g *GameState) grimoireEntry(player string) *GrimoireEntry {
+	if g.Grimoire == nil {
+		g.Grimoire = make(map[string]*GrimoireEntry)
+	}
+	entry := g.Grimoire[player]
+	if entry == nil {
+		entry = &GrimoireEntry{}
+		g.Grimoire[player] = entry
+	}
+	return entry
+}
+
+// onGrimoireCommand handles the /grimoire slash command group.
+func (b *Bot) onGrimoireCommand(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return fmt.Errorf("missing /grimoire subcommand")
+	}
+
+	var user, value, alignment string
+	for _, option := range options[0].Options {
+		switch option.Name {
+		case "player":
+			user = option.UserValue(nil).ID
+		case "character", "reminder":
+			value = option.StringValue()
+		case "alignment":
+			alignment = option.StringValue()
+		}
+	}
+
+	switch options[0].Name {
+	case grimoireCommandAssign:
+		return b.assignCharacter(ctx, s, i, user, value, alignment)
+	case grimoireCommandReminder:
+		return b.toggleReminder(ctx, s, i, user, value)
+	case grimoireCommandShow:
+		return b.showGrimoire(ctx, s, i)
+	}
+
+	return fmt.Errorf("unknown /grimoire subcommand: %s", options[0].Name)
+}
+
+// assignCharacter assigns the character to the seated player. Unless given, the alignment is the
+// default alignment of the character. Reminders of the player are kept.
+func (b *Bot) assignCharacter(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, user, id, alignment string) error {
+	c := findCharacter(id)
+	if c == nil {
+		return fmt.Errorf("unknown character %q", id)
+	}
+	if alignment == "" {
+		alignment = defaultAlignment(c)
+	}
+
+	if err := b.store.Update(i.GuildID, func(state *GameState) error {
+		switch {
+		case !state.Running:
+			return fmt.Errorf("no game is running")
+		case !slices.Contains(state.Players, user):
+			return fmt.Errorf("<@%s> is not seated in the game", user)
+		case state.Script != nil && c.team != teamTraveller && !slices.Contains(state.Script.Characters, c.id):
+			return fmt.Errorf("%s is not on the script %s", c.name, state.Script.Name)
+		}
+		entry := state.grimoireEntry(user)
+		entry.Character, entry.Alignment = c.id, alignment
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	log.Printf("Assigned %s to %s in guild %s.", c.id, user, i.GuildID)
+	return respondEphemeral(ctx, s, i, fmt.Sprintf("<@%s> is the %s (%s).", user, c.name, alignment))
+}
+
+// toggleReminder puts the reminder token on the seated player, or removes it if it is there
+// already.
+func (b *Bot) toggleReminder(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, user, reminder string) error {
+	reminder = strings.TrimSpace(reminder)
+	if reminder == "" {
+		return fmt.Errorf("missing reminder")
+	}
+
+	var removed bool
+	if err := b.store.Update(i.GuildID, func(state *GameState) error {
+		switch {
+		case !state.Running:
+			return fmt.Errorf("no game is running")
+		case !slices.Contains(state.Players, user):
+			return fmt.Errorf("<@%s> is not seated in the game", user)
+		}
+		entry := state.grimoireEntry(user)
+		if index := slices.Index(entry.Reminders, reminder); index >= 0 {
+			entry.Reminders = slices.Delete(entry.Reminders, index, index+1)
+			removed = true
+		} else {
+			entry.Reminders = append(entry.Reminders, reminder)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if removed {
+		return respondEphemeral(ctx, s, i, fmt.Sprintf("Removed reminder %q from <@%s>.", reminder, user))
+	}
+	return respondEphemeral(ctx, s, i, fmt.Sprintf("Added reminder %q to <@%s>.", reminder, user))
+}
+
+// showGrimoire shows the grimoire to the story teller.
+func (b *Bot) showGrimoire(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	state, err := b.store.Get(i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot load game state: %w", err)
+	}
+	if !state.Running {
+		return fmt.Errorf("no game is running")
+	}
+
+	return respondEphemeral(ctx, s, i, renderGrimoire(state))
+}
+
+// renderGrimoire renders the characters, alignments and reminders of all players in seat order,
+// followed by the night order of the current or upcoming night.
+func renderGrimoire(state *GameState) string {
+	lines := []string{"**Grimoire**"}
+	for n, player := range state.Players {
+		line := fmt.Sprintf("%d. <@%s>", n+1, player)
+		if !state.isAlive(player) {
+			line += " 💀"
+		}
+		entry := state.Grimoire[player]
+		if c := entry.character(); c != nil {
+			line += fmt.Sprintf(": %s (%s)", c.name, entry.Alignment)
+		} else {
+			line += ": no character"
+		}
+		if entry != nil && len(entry.Reminders) > 0 {
+			line += fmt.Sprintf(", reminders: %s", strings.Join(entry.Reminders, ", "))
+		}
+		lines = append(lines, line)
+	}
+
+	night := "Other nights"
+	if state.isFirstNight() {
+		night = "First night"
+	}
+	order := renderNightOrder(state, state.nightOrder())
+	if len(order) == 0 {
+		order = []string{"nobody wakes"}
+	}
+	lines = append(lines, fmt.Sprintf("**%s:** %s", night, strings.Join(order, " → ")))
+
+	return strings.Join(lines, "\n")
+}
+
+// character returns the character of the grimoire entry, or nil if none has been assigned.
+func (e *GrimoireEntry) character() *character {
+	if e == nil || e.Character == "" {
+		return nil
+	}
+	return findCharacter(e.Character)
+}
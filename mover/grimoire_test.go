@@ -0,0 +1,201 @@
+package mover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func grimoireInteraction(subcommand string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: slashCommandGrimoire,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: subcommand, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options},
+				},
+			},
+		},
+	}
+}
+
+func assignInteraction(user, character, alignment string) *discordgo.InteractionCreate {
+	options := []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "player", Type: discordgo.ApplicationCommandOptionUser, Value: user},
+		{Name: "character", Type: discordgo.ApplicationCommandOptionString, Value: character},
+	}
+	if alignment != "" {
+		options = append(options, &discordgo.ApplicationCommandInteractionDataOption{
+			Name: "alignment", Type: discordgo.ApplicationCommandOptionString, Value: alignment,
+		})
+	}
+	return grimoireInteraction(grimoireCommandAssign, options...)
+}
+
+func reminderInteraction(user, reminder string) *discordgo.InteractionCreate {
+	return grimoireInteraction(grimoireCommandReminder,
+		&discordgo.ApplicationCommandInteractionDataOption{Name: "player", Type: discordgo.ApplicationCommandOptionUser, Value: user},
+		&discordgo.ApplicationCommandInteractionDataOption{Name: "reminder", Type: discordgo.ApplicationCommandOptionString, Value: reminder},
+	)
+}
+
+func TestGrimoireCommand(t *testing.T) {
+	b, _ := newTestBot(&Config{PerRequestSeconds: 5})
+	d := &fakeDiscordSession{id: "guild"}
+	ctx := context.Background()
+
+	if err := b.onGrimoireCommand(ctx, d, assignInteraction("user1", "imp", "")); err == nil {
+		t.Error("Expected error without a running game, got nil")
+	}
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"user1", "user2", "user3"}
+		state.Dead = []string{"user3"}
+		state.Script = &Script{Name: "Tiny", Characters: []string{"imp", "empath", "poisoner", "saint"}}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	for _, i := range []*discordgo.InteractionCreate{
+		assignInteraction("user1", "Imp", ""),
+		assignInteraction("user2", "empath", ""),
+		// The Poisoner was turned good somehow.
+		assignInteraction("user2", "poisoner", alignmentGood),
+		assignInteraction("user3", "saint", ""),
+		reminderInteraction("user2", "Poisoned"),
+		reminderInteraction("user2", "Drunk"),
+		reminderInteraction("user2", "Poisoned"),
+	} {
+		if err := b.onGrimoireCommand(ctx, d, i); err != nil {
+			t.Fatalf("Cannot update grimoire: %v", err)
+		}
+	}
+	for _, tc := range []struct {
+		desc string
+		i    *discordgo.InteractionCreate
+	}{
+		{"unknown character", assignInteraction("user1", "nobody", "")},
+		{"not on script", assignInteraction("user1", "chef", "")},
+		{"not seated", assignInteraction("storyteller", "imp", "")},
+		{"empty reminder", reminderInteraction("user1", " ")},
+	} {
+		if err := b.onGrimoireCommand(ctx, d, tc.i); err == nil {
+			t.Errorf("%s: Expected error, got nil", tc.desc)
+		}
+	}
+
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	want := map[string]*GrimoireEntry{
+		"user1": {Character: "imp", Alignment: alignmentEvil},
+		"user2": {Character: "poisoner", Alignment: alignmentGood, Reminders: []string{"Drunk"}},
+		"user3": {Character: "saint", Alignment: alignmentGood},
+	}
+	if diff := cmp.Diff(want, state.Grimoire); diff != "" {
+		t.Errorf("Grimoire mismatch (-want, +got):%s\n", diff)
+	}
+
+	if err := b.onGrimoireCommand(ctx, d, grimoireInteraction(grimoireCommandShow)); err != nil {
+		t.Fatalf("Cannot show grimoire: %v", err)
+	}
+	response := d.responses[len(d.responses)-1].Data
+	if response.Flags != discordgo.MessageFlagsEphemeral {
+		t.Error("Expected the grimoire to be ephemeral.")
+	}
+	wantContent := "**Grimoire**\n" +
+		"1. <@user1>: Imp (evil)\n" +
+		"2. <@user2>: Poisoner (good), reminders: Drunk\n" +
+		"3. <@user3> 💀: Saint (good)\n" +
+		"**First night:** Poisoner <@user2>"
+	if diff := cmp.Diff(wantContent, response.Content); diff != "" {
+		t.Errorf("Grimoire content mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestNightOrder(t *testing.T) {
+	state := &GameState{
+		Running: true,
+		Players: []string{"user1", "user2", "user3", "user4", "user5"},
+		Dead:    []string{"user5"},
+		Grimoire: map[string]*GrimoireEntry{
+			"user1": {Character: "empath"},
+			"user2": {Character: "imp"},
+			"user3": {Character: "soldier"},
+			"user4": {Character: "poisoner"},
+			"user5": {Character: "fortuneteller"},
+		},
+	}
+
+	// The Imp does not wake on the first night, dead players do not wake at all.
+	if diff := cmp.Diff([]string{"user4", "user1"}, state.nightOrder()); diff != "" {
+		t.Errorf("First night order mismatch (-want, +got):%s\n", diff)
+	}
+	if diff := cmp.Diff([]string{"user4", "user1", "user2", "user3", "user5"}, state.cottageOrder()); diff != "" {
+		t.Errorf("First night cottage order mismatch (-want, +got):%s\n", diff)
+	}
+
+	state.Day = 1
+	if diff := cmp.Diff([]string{"user4", "user2", "user1"}, state.nightOrder()); diff != "" {
+		t.Errorf("Other night order mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestPrepareNightMovesInNightOrder(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+		CottagesInNightOrder:    true,
+	})
+	d := &fakeDiscordSession{id: "guild"}
+
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"user1", "user2", "user3"}
+		state.Grimoire = map[string]*GrimoireEntry{
+			"user2": {Character: "chef"},
+			"user3": {Character: "poisoner"},
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}},
+		},
+	}
+	if err := b.prepareNightMoves(context.Background(), d, i); err != nil {
+		t.Fatalf("Cannot prepare night moves: %v", err)
+	}
+
+	var plan *movementPlan
+	select {
+	case plan = <-plans:
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	for user, want := range map[string]string{"user3": "cottage1", "user2": "cottage2", "user1": "cottage3"} {
+		if got := plan.moves[user]; got != want {
+			t.Errorf("Expected %s to move to %s, got %s", user, want, got)
+		}
+	}
+}
@@ -2,29 +2,244 @@ package mover
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-type simpleGuildMemberMover struct {
-	sessions []*discordgo.Session
+// memberMoveSession is the part of a discordgo session required to move and mute guild members.
+// Can be exchanged for a fake in unit tests.
+type memberMoveSession interface {
+	GuildMemberMove(guildID string, userID string, channelID *string, options ...discordgo.RequestOption) error
+	GuildMemberMute(guildID string, userID string, mute bool, options ...discordgo.RequestOption) error
+}
+
+// rateLimitBudget tracks the rate limit bucket for member edits of a single session in a guild.
+// Moves and mutes both edit the guild member and share the same bucket.
+type rateLimitBudget struct {
+	limit     int
+	remaining int
+	resetAt   time.Time
+	inFlight  int
+}
+
+// headroom returns the number of requests that can still be sent before the bucket is exhausted.
+func (b *rateLimitBudget) headroom(now time.Time) int {
+	remaining := b.remaining
+	if !now.Before(b.resetAt) {
+		// The bucket has been reset since the last response.
+		remaining = b.limit
+	}
+	return remaining - b.inFlight
+}
+
+// update updates the budget from the rate limit headers of a discord API response.
+func (b *rateLimitBudget) update(now time.Time, headers http.Header) {
+	if v, err := strconv.Atoi(headers.Get("X-RateLimit-Limit")); err == nil {
+		b.limit = v
+	}
+	if v, err := strconv.Atoi(headers.Get("X-RateLimit-Remaining")); err == nil {
+		b.remaining = v
+	}
+	if v, err := strconv.ParseFloat(headers.Get("X-RateLimit-Reset-After"), 64); err == nil {
+		b.resetAt = now.Add(time.Duration(v * float64(time.Second)))
+	}
+}
+
+// moverSession is a single bot session used by the mover, along with its rate limit budgets.
+type moverSession struct {
+	name    string
+	session memberMoveSession
+	client  *http.Client
+	// budgets maps guild IDs to the session's rate limit budget in that guild.
+	budgets map[string]*rateLimitBudget
+}
+
+// budget returns the session's rate limit budget for the guild. Sessions start with a budget of
+// a single request until discord tells us otherwise.
+func (s *moverSession) budget(guild string) *rateLimitBudget {
+	b, ok := s.budgets[guild]
+	if !ok {
+		b = &rateLimitBudget{limit: 1, remaining: 1}
+		s.budgets[guild] = b
+	}
+	return b
+}
+
+// rateLimitedMover moves and mutes guild members using all bot sessions. Every request is sent to
+// the session with the most remaining rate limit budget in the guild, as reported by discord's
+// rate limit headers. Requests that are rate limited anyway are retried on a different session.
+type rateLimitedMover struct {
+	sessions []*moverSession
 	counter  int
 	mu       sync.Mutex
 }
 
-func (m *simpleGuildMemberMover) next() *discordgo.Session {
+// newRateLimitedMover creates a new mover using the given bot sessions.
+func newRateLimitedMover(sessions []*discordgo.Session) *rateLimitedMover {
+	m := &rateLimitedMover{}
+	for _, s := range sessions {
+		m.sessions = append(m.sessions, &moverSession{
+			name:    s.State.User.Username,
+			session: s,
+			client:  s.Client,
+			budgets: make(map[string]*rateLimitBudget),
+		})
+	}
+	return m
+}
+
+// acquire reserves a request on the session with the most headroom for the guild, ignoring all
+// excluded sessions. Sessions with equal headroom are used in round-robin order. If even the best
+// session has no headroom left, acquire also returns how long to wait until its bucket resets.
+// Returns nil if all sessions are excluded.
+func (m *rateLimitedMover) acquire(guild string, exclude map[*moverSession]bool) (*moverSession, time.Duration) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	idx := m.counter % len(m.sessions)
+	now := time.Now()
+	var best *moverSession
+	var bestHeadroom int
+	for i := range m.sessions {
+		s := m.sessions[(m.counter+i)%len(m.sessions)]
+		if exclude[s] {
+			continue
+		}
+		budget := s.budget(guild)
+		headroom := budget.headroom(now)
+		switch {
+		case best == nil, headroom > bestHeadroom:
+			best, bestHeadroom = s, headroom
+		case headroom == bestHeadroom && headroom <= 0 && budget.resetAt.Before(best.budget(guild).resetAt):
+			// No session has headroom left, prefer the one whose bucket resets first.
+			best = s
+		}
+	}
 	m.counter += 1
-	return m.sessions[idx]
+
+	if best == nil {
+		return nil, 0
+	}
+
+	b := best.budget(guild)
+	b.inFlight++
+	if bestHeadroom > 0 {
+		return best, 0
+	}
+	return best, b.resetAt.Sub(now)
 }
 
-func (m *simpleGuildMemberMover) Move(ctx context.Context, guild, user, channel string) error {
-	s := m.next()
-	log.Printf("Using session %s to move %s to %s.", s.State.User.Username, user, channel)
-	return s.GuildMemberMove(guild, user, &channel, discordgo.WithContext(ctx))
+// release returns a request reserved by acquire and updates the session's budget with the rate
+// limit headers of the response.
+func (m *rateLimitedMover) release(s *moverSession, guild string, headers http.Header, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	b := s.budget(guild)
+	b.inFlight--
+	if headers != nil {
+		b.update(now, headers)
+	}
+
+	var rateLimitErr *discordgo.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		b.remaining = 0
+		if resetAt := now.Add(rateLimitErr.RetryAfter); resetAt.After(b.resetAt) {
+			b.resetAt = resetAt
+		}
+	}
+}
+
+// Move moves the user to the channel. Rate limited moves are retried on the other sessions. Only
+// returns a rate limit error if every session is rate limited.
+func (m *rateLimitedMover) Move(ctx context.Context, guild, user, channel string) error {
+	return m.do(ctx, guild, fmt.Sprintf("move %s to %s", user, channel), func(s memberMoveSession, options ...discordgo.RequestOption) error {
+		return s.GuildMemberMove(guild, user, &channel, options...)
+	})
+}
+
+// Mute server-mutes or un-mutes the user. Rate limited requests are retried on the other sessions.
+// Only returns a rate limit error if every session is rate limited.
+func (m *rateLimitedMover) Mute(ctx context.Context, guild, user string, mute bool) error {
+	desc := fmt.Sprintf("unmute %s", user)
+	if mute {
+		desc = fmt.Sprintf("mute %s", user)
+	}
+	return m.do(ctx, guild, desc, func(s memberMoveSession, options ...discordgo.RequestOption) error {
+		return s.GuildMemberMute(guild, user, mute, options...)
+	})
+}
+
+// do sends the guild member request using the session with the most headroom. Rate limited
+// requests are retried on the other sessions.
+func (m *rateLimitedMover) do(ctx context.Context, guild, desc string, request func(s memberMoveSession, options ...discordgo.RequestOption) error) error {
+	tried := make(map[*moverSession]bool)
+	var err error
+	for {
+		s, wait := m.acquire(guild, tried)
+		if s == nil {
+			return err
+		}
+		if wait > 0 {
+			log.Printf("All sessions are rate limited, waiting %v for session %s.", wait, s.name)
+			select {
+			case <-ctx.Done():
+				m.release(s, guild, nil, nil)
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		log.Printf("Using session %s to %s.", s.name, desc)
+		var headers http.Header
+		err = request(s.session,
+			discordgo.WithContext(ctx),
+			discordgo.WithRetryOnRatelimit(false),
+			discordgo.WithClient(recordHeaders(s.client, &headers)))
+		m.release(s, guild, headers, err)
+
+		var rateLimitErr *discordgo.RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			return err
+		}
+		log.Printf("Session %s is rate limited for %v, trying a different session.", s.name, rateLimitErr.RetryAfter)
+		tried[s] = true
+	}
+}
+
+// roundTripFunc implements http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// recordHeaders returns a copy of the client that stores the headers of the last response in
+// headers. Used to read the rate limit headers of a single request.
+func recordHeaders(client *http.Client, headers *http.Header) *http.Client {
+	if client == nil {
+		return nil
+	}
+
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	recorder := *client
+	recorder.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := transport.RoundTrip(req)
+		if resp != nil {
+			*headers = resp.Header
+		}
+		return resp, err
+	})
+	return &recorder
 }
@@ -1,23 +1,69 @@
 package mover
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/go-cmp/cmp"
 )
 
-func TestNext(t *testing.T) {
-	m := &simpleGuildMemberMover{
-		sessions: []*discordgo.Session{
-			{Token: "a"}, {Token: "b"}, {Token: "c"},
-		},
+type fakeMoveSession struct {
+	rateLimited bool
+	moves       []string
+	mutes       []string
+}
+
+func (f *fakeMoveSession) rateLimitErr(guildID, userID string) error {
+	return &discordgo.RateLimitError{RateLimit: &discordgo.RateLimit{
+		TooManyRequests: &discordgo.TooManyRequests{RetryAfter: time.Minute},
+		URL:             discordgo.EndpointGuildMember(guildID, userID),
+	}}
+}
+
+func (f *fakeMoveSession) GuildMemberMove(guildID string, userID string, channelID *string, options ...discordgo.RequestOption) error {
+	if f.rateLimited {
+		return f.rateLimitErr(guildID, userID)
+	}
+	f.moves = append(f.moves, userID)
+	return nil
+}
+
+func (f *fakeMoveSession) GuildMemberMute(guildID string, userID string, mute bool, options ...discordgo.RequestOption) error {
+	if f.rateLimited {
+		return f.rateLimitErr(guildID, userID)
+	}
+	f.mutes = append(f.mutes, fmt.Sprintf("%s:%t", userID, mute))
+	return nil
+}
+
+func newTestMover(sessions map[string]*fakeMoveSession, names ...string) *rateLimitedMover {
+	m := &rateLimitedMover{}
+	for _, name := range names {
+		m.sessions = append(m.sessions, &moverSession{
+			name:    name,
+			session: sessions[name],
+			budgets: make(map[string]*rateLimitBudget),
+		})
 	}
+	return m
+}
+
+func TestAcquireRoundRobin(t *testing.T) {
+	m := newTestMover(nil, "a", "b", "c")
 
+	// Without any rate limit information, all sessions are equally good.
 	var got []string
 	for i := 0; i < 7; i++ {
-		s := m.next()
-		got = append(got, s.Token)
+		s, wait := m.acquire("guild", nil)
+		if wait != 0 {
+			t.Fatalf("Expected no wait time for fresh sessions, got %v", wait)
+		}
+		got = append(got, s.name)
+		m.release(s, "guild", nil, nil)
 	}
 
 	want := []string{"a", "b", "c", "a", "b", "c", "a"}
@@ -25,3 +71,106 @@ func TestNext(t *testing.T) {
 		t.Fatalf("Unexpected order of sessions received from mover (-want, +got):%s\n", diff)
 	}
 }
+
+func TestAcquirePrefersHeadroom(t *testing.T) {
+	m := newTestMover(nil, "a", "b", "c")
+
+	now := time.Now()
+	m.sessions[0].budget("guild").update(now, http.Header{
+		"X-Ratelimit-Limit":       []string{"10"},
+		"X-Ratelimit-Remaining":   []string{"1"},
+		"X-Ratelimit-Reset-After": []string{"5.5"},
+	})
+	m.sessions[1].budget("guild").update(now, http.Header{
+		"X-Ratelimit-Limit":       []string{"10"},
+		"X-Ratelimit-Remaining":   []string{"3"},
+		"X-Ratelimit-Reset-After": []string{"5.5"},
+	})
+	m.sessions[2].budget("guild").update(now, http.Header{
+		"X-Ratelimit-Limit":       []string{"10"},
+		"X-Ratelimit-Remaining":   []string{"0"},
+		"X-Ratelimit-Reset-After": []string{"1"},
+	})
+
+	// Session b has 3 requests left, a has 1 and c has none. Requests are reserved until released.
+	var got []string
+	for i := 0; i < 4; i++ {
+		s, wait := m.acquire("guild", nil)
+		if wait != 0 {
+			t.Fatalf("Expected no wait time while sessions have headroom, got %v", wait)
+		}
+		got = append(got, s.name)
+	}
+
+	want := []string{"b", "b", "a", "b"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Unexpected order of sessions received from mover (-want, +got):%s\n", diff)
+	}
+
+	// All buckets are exhausted now. Session c resets first.
+	s, wait := m.acquire("guild", nil)
+	if s.name != "c" || wait <= 0 || wait > time.Second {
+		t.Fatalf("Expected to wait up to 1s for session c, got session %s and wait time %v", s.name, wait)
+	}
+
+	// Budgets are tracked per guild.
+	if s, wait := m.acquire("other guild", nil); wait != 0 {
+		t.Fatalf("Expected no wait time in a different guild, got session %s and wait time %v", s.name, wait)
+	}
+}
+
+func TestMoveAvoidsRateLimitedSession(t *testing.T) {
+	sessions := map[string]*fakeMoveSession{
+		"a": {rateLimited: true},
+		"b": {},
+	}
+	m := newTestMover(sessions, "a", "b")
+
+	ctx := context.Background()
+	for _, user := range []string{"user1", "user2", "user3"} {
+		if err := m.Move(ctx, "guild", user, "channel"); err != nil {
+			t.Fatalf("Cannot move %s: %v", user, err)
+		}
+	}
+
+	want := []string{"user1", "user2", "user3"}
+	if diff := cmp.Diff(want, sessions["b"].moves); diff != "" {
+		t.Fatalf("Unexpected moves of session b (-want, +got):%s\n", diff)
+	}
+
+	// Once every session is rate limited, the move waits for a bucket to reset and gives up at the
+	// deadline.
+	sessions["b"].rateLimited = true
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := m.Move(ctx, "guild", "user4", "channel"); err == nil {
+		t.Fatal("Expected move to fail when all sessions are rate limited.")
+	}
+}
+
+func TestMuteSharesBudgetWithMoves(t *testing.T) {
+	sessions := map[string]*fakeMoveSession{
+		"a": {rateLimited: true},
+		"b": {},
+	}
+	m := newTestMover(sessions, "a", "b")
+
+	ctx := context.Background()
+	if err := m.Mute(ctx, "guild", "user1", true); err != nil {
+		t.Fatalf("Cannot mute user1: %v", err)
+	}
+	if err := m.Move(ctx, "guild", "user1", "channel"); err != nil {
+		t.Fatalf("Cannot move user1: %v", err)
+	}
+	if err := m.Mute(ctx, "guild", "user1", false); err != nil {
+		t.Fatalf("Cannot unmute user1: %v", err)
+	}
+
+	// Session a was rate limited by the first mute, so everything else goes to session b.
+	if diff := cmp.Diff([]string{"user1:true", "user1:false"}, sessions["b"].mutes); diff != "" {
+		t.Errorf("Unexpected mutes of session b (-want, +got):%s\n", diff)
+	}
+	if diff := cmp.Diff([]string{"user1"}, sessions["b"].moves); diff != "" {
+		t.Errorf("Unexpected moves of session b (-want, +got):%s\n", diff)
+	}
+}
@@ -0,0 +1,112 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// nextInNightOrder handles the "Next in Night Order" button. The story teller who pressed it is
+// moved into the cottage of the next player who wakes tonight according to the grimoire. Players
+// who are not in a cottage are skipped.
+func (b *Bot) nextInNightOrder(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	state, err := b.store.Get(i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot load game state: %w", err)
+	}
+	switch {
+	case !state.Running:
+		return fmt.Errorf("no game is running")
+	case state.Phase != phaseNight:
+		return fmt.Errorf("the night has not started yet")
+	}
+
+	vs, err := b.buildDiscordVoiceState(ctx, s, i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot build voice state: %w", err)
+	}
+	cottageIDs := make(map[string]bool)
+	for _, cottage := range vs.cottages {
+		cottageIDs[cottage.ID] = true
+	}
+
+	order := state.nightOrder()
+	var next, cottageID string
+	var skipped []string
+	for _, player := range order {
+		if slices.Contains(state.NightVisits, player) {
+			continue
+		}
+		userVoiceState := vs.userToVoiceState[player]
+		if userVoiceState == nil || !cottageIDs[userVoiceState.ChannelID] {
+			skipped = append(skipped, player)
+			continue
+		}
+		next, cottageID = player, userVoiceState.ChannelID
+		break
+	}
+
+	var lines []string
+	if len(skipped) > 0 {
+		lines = append(lines, fmt.Sprintf("Skipped %s, not in a cottage.", strings.Join(renderNightOrder(state, skipped), ", ")))
+	}
+	if next == "" {
+		if err := b.recordNightVisits(i.GuildID, skipped); err != nil {
+			return err
+		}
+		lines = append(lines, "Everyone in the night order has been visited tonight.")
+		return respondEphemeral(ctx, s, i, strings.Join(lines, "\n"))
+	}
+	lines = append(lines, fmt.Sprintf("🌙 Next in night order (%d/%d): %s", slices.Index(order, next)+1, len(order), renderNightOrder(state, []string{next})[0]))
+	log.Printf("Moving story teller %s to %s in guild %s.", i.Member.User.ID, next, i.GuildID)
+
+	plan := &movementPlan{
+		moves:       map[string]string{i.Member.User.ID: cottageID},
+		guild:       i.GuildID,
+		description: strings.Join(lines, "\n"),
+	}
+	if userVoiceState := vs.userToVoiceState[i.Member.User.ID]; userVoiceState != nil && userVoiceState.ChannelID == cottageID {
+		plan.moves = nil
+		plan.inPlace = map[string]string{i.Member.User.ID: cottageID}
+	}
+	// Players only count as visited once the story teller arrived, otherwise the next press
+	// tries again.
+	plan.onDone = func(report *movementReport) {
+		result := report.results[i.Member.User.ID]
+		if result == nil || (result.status != moveStatusMoved && result.status != moveStatusAlreadyInPlace) {
+			return
+		}
+		if err := b.recordNightVisits(i.GuildID, append(skipped, next)); err != nil {
+			log.Printf("Cannot record night visits in guild %s: %v", i.GuildID, err)
+		}
+	}
+	_, err = b.dispatchPlan(ctx, s, i, plan)
+	return err
+}
+
+// recordNightVisits marks the players as visited tonight.
+func (b *Bot) recordNightVisits(guildID string, players []string) error {
+	if len(players) == 0 {
+		return nil
+	}
+	if err := b.store.Update(guildID, func(state *GameState) error {
+		state.NightVisits = append(state.NightVisits, players...)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("cannot store game state: %w", err)
+	}
+	return nil
+}
+
+// renderNightOrder renders the character and mention of each player, who all have a character.
+func renderNightOrder(state *GameState, players []string) []string {
+	var parts []string
+	for _, player := range players {
+		parts = append(parts, fmt.Sprintf("%s <@%s>", state.Grimoire[player].character().name, player))
+	}
+	return parts
+}
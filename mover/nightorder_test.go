@@ -0,0 +1,118 @@
+package mover
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNextInNightOrder(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user1", ChannelID: "cottage1"},
+			{UserID: "user2", ChannelID: "townsquare"},
+			{UserID: "user3", ChannelID: "cottage3"},
+			{UserID: "storyteller", ChannelID: "cottage5"},
+		},
+	}
+	ctx := context.Background()
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionMessageComponent,
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}},
+			Data:    discordgo.MessageComponentInteractionData{CustomID: buttonNextNight},
+		},
+	}
+
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Phase = phaseDay
+		state.Day = 1
+		state.Players = []string{"user1", "user2", "user3"}
+		state.Grimoire = map[string]*GrimoireEntry{
+			"user1": {Character: "empath"},
+			"user2": {Character: "poisoner"},
+			"user3": {Character: "imp"},
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+	if err := b.nextInNightOrder(ctx, d, i); err == nil {
+		t.Error("Expected error during the day, got nil")
+	}
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.enterPhase(phaseNight)
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	// The Poisoner is not in a cottage and is skipped, the Imp is next. Players only count as
+	// visited once the story teller arrived.
+	for _, want := range []struct {
+		cottage     string
+		description string
+		status      moveStatus
+	}{
+		{"cottage3", "Skipped Poisoner <@user2>, not in a cottage.\n🌙 Next in night order (2/3): Imp <@user3>", moveStatusFailed},
+		{"cottage3", "Skipped Poisoner <@user2>, not in a cottage.\n🌙 Next in night order (2/3): Imp <@user3>", moveStatusMoved},
+		{"cottage1", "🌙 Next in night order (3/3): Empath <@user1>", moveStatusMoved},
+	} {
+		if err := b.nextInNightOrder(ctx, d, i); err != nil {
+			t.Fatalf("Cannot move to the next player in night order: %v", err)
+		}
+		select {
+		case plan := <-plans:
+			if diff := cmp.Diff(map[string]string{"storyteller": want.cottage}, plan.moves); diff != "" {
+				t.Errorf("Moves mismatch (-want, +got):%s\n", diff)
+			}
+			if plan.description != want.description {
+				t.Errorf("Unexpected description %q, want %q", plan.description, want.description)
+			}
+			plan.onDone(&movementReport{results: map[string]*moveResult{"storyteller": {channel: want.cottage, status: want.status}}})
+		case <-time.After(time.Second):
+			t.Fatal("Expected to receive plan, got nothing.")
+		}
+		b.plans.Wait()
+	}
+
+	if err := b.nextInNightOrder(ctx, d, i); err != nil {
+		t.Fatalf("Cannot move to the next player in night order: %v", err)
+	}
+	if got := d.responses[len(d.responses)-1].Data.Content; !strings.HasPrefix(got, "Everyone in the night order has been visited tonight.") {
+		t.Errorf("Unexpected response %q", got)
+	}
+
+	// The next night starts over.
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.enterPhase(phaseDay)
+		state.enterPhase(phaseNight)
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if len(state.NightVisits) != 0 {
+		t.Errorf("Expected no visits in a new night, got %v", state.NightVisits)
+	}
+}
@@ -2,11 +2,15 @@ package mover
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/bwmarrin/discordgo"
 )
 
 const (
@@ -18,11 +22,26 @@ const (
 type movementPlan struct {
 	// moves maps user IDs to channel IDs.
 	moves map[string]string
+	// inPlace maps user IDs to channel IDs for all users that are already where they should be.
+	inPlace map[string]string
+	// mutes maps user IDs to whether they should be server-muted (true) or un-muted (false) once
+	// all moves are done. Optional.
+	mutes map[string]bool
 	guild string
+
+	// interaction that requested the plan. Its deferred response is edited with the movement
+	// report once the plan has been executed. Optional.
+	interaction *discordgo.Interaction
+	session     discordSession
+
+	// onDone is called with the movement report once the plan has been executed. Optional.
+	onDone func(report *movementReport)
+	// description is shown above the movement report. Optional.
+	description string
 }
 
 func (p *movementPlan) String() string {
-	if len(p.moves) == 0 {
+	if len(p.moves) == 0 && len(p.mutes) == 0 {
 		return fmt.Sprintf("No movements required for guild %s", p.guild)
 	}
 
@@ -30,61 +49,257 @@ func (p *movementPlan) String() string {
 	for user, channel := range p.moves {
 		parts = append(parts, fmt.Sprintf("[Move user %s to channel %s]", user, channel))
 	}
+	for user, mute := range p.mutes {
+		parts = append(parts, fmt.Sprintf("[Mute user %s: %t]", user, mute))
+	}
 
 	return fmt.Sprintf("Moving members of guild %s: %s", p.guild, strings.Join(parts, ", "))
 }
 
 type guildMemberMover interface {
 	Move(ctx context.Context, guild, user, channel string) error
+	Mute(ctx context.Context, guild, user string, mute bool) error
+}
+
+// moveStatus is the outcome of a single user's move.
+type moveStatus int
+
+const (
+	moveStatusMoved moveStatus = iota
+	moveStatusAlreadyInPlace
+	moveStatusFailed
+	moveStatusSkipped
+)
+
+// moveResult is the outcome of a single user's move.
+type moveResult struct {
+	channel string
+	status  moveStatus
+	// err is the reason why the move failed or was skipped.
+	err error
 }
 
-// Execute executes all movements required to enter a new phase.
-func (p *movementPlan) Execute(ctx context.Context, cfg *Config, m guildMemberMover) error {
-	tasks := make(chan string, len(p.moves))
+// muteResult is the outcome of a single user's mute or un-mute.
+type muteResult struct {
+	mute bool
+	err  error
+}
+
+// movementReport contains the outcome of a movement plan for every user.
+type movementReport struct {
+	guild string
+	// results maps user IDs to their move results.
+	results map[string]*moveResult
+	// mutes maps user IDs to their mute results.
+	mutes map[string]*muteResult
+}
+
+// Err returns an error iff any user could not be moved.
+func (r *movementReport) Err() error {
+	var failed, skipped int
+	for _, result := range r.results {
+		switch result.status {
+		case moveStatusFailed:
+			failed++
+		case moveStatusSkipped:
+			skipped++
+		}
+	}
+
+	var muteFailed int
+	for _, result := range r.mutes {
+		if result.err != nil {
+			muteFailed++
+		}
+	}
+
+	if failed+skipped > 0 {
+		return fmt.Errorf("%d user(s) could not be moved, %d user(s) were skipped", failed, skipped)
+	}
+	if muteFailed > 0 {
+		return fmt.Errorf("%d user(s) could not be muted or un-muted", muteFailed)
+	}
+	return nil
+}
+
+// Summary returns a short human readable summary of the report, suitable for discord messages.
+func (r *movementReport) Summary() string {
+	byStatus := make(map[moveStatus][]string)
+	for user, result := range r.results {
+		byStatus[result.status] = append(byStatus[result.status], user)
+	}
+	for _, users := range byStatus {
+		sort.Strings(users)
+	}
+
+	if len(r.results) == 0 && len(r.mutes) == 0 {
+		return "No movements required."
+	}
+
+	var lines []string
+	if n := len(byStatus[moveStatusMoved]); n > 0 {
+		lines = append(lines, fmt.Sprintf("✅ Moved: %d", n))
+	}
+	if n := len(byStatus[moveStatusAlreadyInPlace]); n > 0 {
+		lines = append(lines, fmt.Sprintf("☑️ Already in place: %d", n))
+	}
+	if users := byStatus[moveStatusFailed]; len(users) > 0 {
+		var parts []string
+		for _, user := range users {
+			parts = append(parts, fmt.Sprintf("<@%s> (%v)", user, r.results[user].err))
+		}
+		lines = append(lines, fmt.Sprintf("❌ Failed: %s", strings.Join(parts, ", ")))
+	}
+	if users := byStatus[moveStatusSkipped]; len(users) > 0 {
+		var parts []string
+		for _, user := range users {
+			parts = append(parts, fmt.Sprintf("<@%s>", user))
+		}
+		lines = append(lines, fmt.Sprintf("⏭️ Skipped, deadline passed: %s", strings.Join(parts, ", ")))
+	}
+
+	var muted, unmuted int
+	var muteFailures []string
+	for user, result := range r.mutes {
+		switch {
+		case result.err != nil:
+			muteFailures = append(muteFailures, user)
+		case result.mute:
+			muted++
+		default:
+			unmuted++
+		}
+	}
+	if muted > 0 {
+		lines = append(lines, fmt.Sprintf("🔇 Muted: %d", muted))
+	}
+	if unmuted > 0 {
+		lines = append(lines, fmt.Sprintf("🔊 Un-muted: %d", unmuted))
+	}
+	if len(muteFailures) > 0 {
+		sort.Strings(muteFailures)
+		var parts []string
+		for _, user := range muteFailures {
+			parts = append(parts, fmt.Sprintf("<@%s> (%v)", user, r.mutes[user].err))
+		}
+		lines = append(lines, fmt.Sprintf("❌ Mute failed: %s", strings.Join(parts, ", ")))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Execute executes all movements required to enter a new phase and reports the outcome for every
+// user in the plan. Users are only muted or un-muted once all moves are done.
+func (p *movementPlan) Execute(ctx context.Context, cfg *Config, m guildMemberMover) *movementReport {
+	report := &movementReport{
+		guild:   p.guild,
+		results: make(map[string]*moveResult),
+		mutes:   make(map[string]*muteResult),
+	}
+	for user, channel := range p.inPlace {
+		report.results[user] = &moveResult{channel: channel, status: moveStatusAlreadyInPlace}
+	}
+
+	var users []string
 	for user := range p.moves {
+		users = append(users, user)
+	}
+	for user, result := range executeConcurrently(cfg.MaxConcurrentRequests, users, func(user string) *moveResult {
+		return executeSingleMove(ctx, p.guild, user, p.moves[user], len(p.moves), m)
+	}) {
+		report.results[user] = result
+	}
+
+	// Users that did not end up where they should be are not muted.
+	users = nil
+	for user := range p.mutes {
+		if result, ok := report.results[user]; ok && result.status != moveStatusMoved && result.status != moveStatusAlreadyInPlace {
+			continue
+		}
+		users = append(users, user)
+	}
+	for user, result := range executeConcurrently(cfg.MaxConcurrentRequests, users, func(user string) *muteResult {
+		return &muteResult{mute: p.mutes[user], err: m.Mute(ctx, p.guild, user, p.mutes[user])}
+	}) {
+		report.mutes[user] = result
+	}
+
+	return report
+}
+
+// executeConcurrently runs execute for all users with the given number of workers and returns the
+// results by user.
+func executeConcurrently[T any](workers int, users []string, execute func(user string) T) map[string]T {
+	tasks := make(chan string, len(users))
+	for _, user := range users {
 		tasks <- user
 	}
+	close(tasks)
 
-	results := make(chan error)
-	for i := 0; i < cfg.MaxConcurrentRequests; i++ {
+	type userResult struct {
+		user   string
+		result T
+	}
+	results := make(chan userResult)
+	for i := 0; i < workers; i++ {
 		go func() {
 			for user := range tasks {
-				results <- executeSingleMove(ctx, p.guild, user, p.moves[user], len(p.moves), m)
+				results <- userResult{user, execute(user)}
 			}
 		}()
 	}
 
-	var err error
-
-	for range p.moves {
-		if e := <-results; e != nil {
-			err = e
-		}
+	byUser := make(map[string]T)
+	for range users {
+		r := <-results
+		byUser[r.user] = r.result
 	}
-
-	close(results)
-	close(tasks)
-	return err
+	return byUser
 }
 
-func executeSingleMove(ctx context.Context, guild, user, channel string, planSize int, m guildMemberMover) error {
+func executeSingleMove(ctx context.Context, guild, user, channel string, planSize int, m guildMemberMover) *moveResult {
+	// lastErr is nil as long as no attempt has completed. A user whose first attempt never got
+	// past the deadline is skipped rather than failed.
+	var lastErr error
 	for i := 0; i < maxAttemptsPerUser; i++ {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if i == 0 {
-				wait := (1000.0 / float64(planSize)) * rand.Float64()
-				time.Sleep(time.Duration(wait) * time.Millisecond)
+		if i == 0 {
+			wait := (1000.0 / float64(planSize)) * rand.Float64()
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(wait) * time.Millisecond):
 			}
-			if err := m.Move(ctx, guild, user, channel); err != nil {
-				log.Printf("Attempt %d to move %s to %s failed: %v", i+1, user, channel, err)
-				time.Sleep(50 * time.Millisecond)
-			} else {
-				return nil
+		}
+		if err := ctx.Err(); err != nil {
+			if lastErr == nil {
+				return &moveResult{channel: channel, status: moveStatusSkipped, err: err}
 			}
+			return &moveResult{channel: channel, status: moveStatusFailed, err: err}
+		}
+
+		err := m.Move(ctx, guild, user, channel)
+		if err == nil {
+			return &moveResult{channel: channel, status: moveStatusMoved}
+		}
+		if ctx.Err() != nil {
+			if lastErr == nil && errors.Is(err, context.DeadlineExceeded) {
+				// The first attempt was cut short by the deadline.
+				return &moveResult{channel: channel, status: moveStatusSkipped, err: err}
+			}
+			// Errors that arrive right at the deadline are real failures.
+			return &moveResult{channel: channel, status: moveStatusFailed, err: err}
+		}
+		log.Printf("Attempt %d to move %s to %s failed: %v", i+1, user, channel, err)
+		lastErr = err
+		// The mover already waits for rate limit buckets to reset, no need to sleep here.
+		var rateLimitErr *discordgo.RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			time.Sleep(50 * time.Millisecond)
 		}
 	}
 
-	return fmt.Errorf("could not move user %s after %d attempts", user, maxAttemptsPerUser)
+	return &moveResult{
+		channel: channel,
+		status:  moveStatusFailed,
+		err:     fmt.Errorf("giving up after %d attempts: %w", maxAttemptsPerUser, lastErr),
+	}
 }
@@ -3,8 +3,10 @@ package mover
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -13,6 +15,7 @@ type fakeMover struct {
 	*fakeDiscordSession
 	failures         map[string]int
 	numTotalFailures int
+	muted            map[string]bool
 	mu               sync.Mutex
 }
 
@@ -41,6 +44,20 @@ func (f *fakeMover) Move(ctx context.Context, guild, user, channel string) error
 	return nil
 }
 
+func (f *fakeMover) Mute(ctx context.Context, guild, user string, mute bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.userToChannelMap[user]; !ok {
+		return fmt.Errorf("unknown user: %v", user)
+	}
+	if f.muted == nil {
+		f.muted = make(map[string]bool)
+	}
+	f.muted[user] = mute
+	return nil
+}
+
 func TestExecuteMovementPlan(t *testing.T) {
 	cfg := &Config{
 		Tokens:                  []string{"a", "b", "c"},
@@ -82,7 +99,7 @@ func TestExecuteMovementPlan(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	if err := plan.Execute(ctx, cfg, fm); err != nil {
+	if err := plan.Execute(ctx, cfg, fm).Err(); err != nil {
 		t.Fatalf("Cannot execute plan: %v", err)
 	}
 
@@ -91,3 +108,191 @@ func TestExecuteMovementPlan(t *testing.T) {
 		t.Fatalf("End state is not as expected (-want, +got):\n%s", diff)
 	}
 }
+
+func TestMovementReport(t *testing.T) {
+	cfg := &Config{
+		MaxConcurrentRequests: 2,
+	}
+
+	d := &fakeDiscordSession{
+		id: "guild",
+		userToChannelMap: map[string]string{
+			"user1": "somewhere",
+			"user2": "townsquare",
+		},
+	}
+	fm := &fakeMover{
+		fakeDiscordSession: d,
+		failures:           make(map[string]int),
+		// Do not emulate any additional failures.
+		numTotalFailures: 10,
+	}
+
+	plan := &movementPlan{
+		guild: "guild",
+		moves: map[string]string{
+			"user1":   "townsquare",
+			"unknown": "townsquare",
+		},
+		inPlace: map[string]string{
+			"user2": "townsquare",
+		},
+	}
+
+	report := plan.Execute(context.Background(), cfg, fm)
+	want := map[string]moveStatus{
+		"user1":   moveStatusMoved,
+		"user2":   moveStatusAlreadyInPlace,
+		"unknown": moveStatusFailed,
+	}
+	got := make(map[string]moveStatus)
+	for user, result := range report.results {
+		got[user] = result.status
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Unexpected move results (-want, +got):\n%s", diff)
+	}
+	if report.Err() == nil {
+		t.Error("Expected report with failed move to return an error.")
+	}
+
+	summary := report.Summary()
+	for _, s := range []string{"Moved: 1", "Already in place: 1", "Failed: <@unknown> (", "unknown user: unknown"} {
+		if !strings.Contains(summary, s) {
+			t.Errorf("Expected summary %q to contain %q", summary, s)
+		}
+	}
+
+	// Moves are skipped once the deadline has passed.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report = plan.Execute(ctx, cfg, fm)
+	if got := report.results["user1"].status; got != moveStatusSkipped {
+		t.Errorf("Expected move after deadline to be skipped, got status %v", got)
+	}
+	if summary := report.Summary(); !strings.Contains(summary, "Skipped, deadline passed: <@unknown>, <@user1>") {
+		t.Errorf("Expected summary %q to list skipped users", summary)
+	}
+}
+
+// slowMover blocks every request until the deadline passes.
+type slowMover struct{}
+
+func (slowMover) Move(ctx context.Context, guild, user, channel string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (slowMover) Mute(ctx context.Context, guild, user string, mute bool) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestDeadlineDuringFirstAttempt(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result := executeSingleMove(ctx, "guild", "user1", "channel1", 1000, slowMover{})
+	if result.status != moveStatusSkipped {
+		t.Errorf("Expected move cut short by the deadline to be skipped, got status %v (%v)", result.status, result.err)
+	}
+}
+
+// lateMover fails every request once the deadline has passed, like a request whose error
+// response arrives right at the deadline.
+type lateMover struct{ err error }
+
+func (l lateMover) Move(ctx context.Context, guild, user, channel string) error {
+	<-ctx.Done()
+	return l.err
+}
+
+func (l lateMover) Mute(ctx context.Context, guild, user string, mute bool) error {
+	<-ctx.Done()
+	return l.err
+}
+
+func TestFailureAtDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// A large plan keeps the random wait before the first request short.
+	missingPermissions := fmt.Errorf("HTTP 403 Forbidden, Missing Permissions")
+	result := executeSingleMove(ctx, "guild", "user1", "channel1", 1000, lateMover{missingPermissions})
+	if result.status != moveStatusFailed || result.err != missingPermissions {
+		t.Errorf("Expected move to fail with %v, got status %v (%v)", missingPermissions, result.status, result.err)
+	}
+}
+
+func TestExecuteMutes(t *testing.T) {
+	cfg := &Config{
+		MaxConcurrentRequests: 2,
+	}
+
+	d := &fakeDiscordSession{
+		id: "guild",
+		userToChannelMap: map[string]string{
+			"user1": "somewhere",
+			"user2": "cottage2",
+			"user3": "cottage3",
+		},
+	}
+	fm := &fakeMover{
+		fakeDiscordSession: d,
+		failures:           make(map[string]int),
+		// Do not emulate any additional failures.
+		numTotalFailures: 10,
+	}
+
+	plan := &movementPlan{
+		guild: "guild",
+		moves: map[string]string{
+			"user1":   "cottage1",
+			"unknown": "cottage4",
+		},
+		inPlace: map[string]string{
+			"user2": "cottage2",
+		},
+		mutes: map[string]bool{
+			"user1":   true,
+			"user2":   true,
+			"user3":   false,
+			"unknown": true,
+		},
+	}
+
+	report := plan.Execute(context.Background(), cfg, fm)
+
+	// Users whose move failed are not muted.
+	want := map[string]bool{
+		"user1": true,
+		"user2": true,
+		"user3": false,
+	}
+	if diff := cmp.Diff(want, fm.muted); diff != "" {
+		t.Errorf("Unexpected mutes (-want, +got):\n%s", diff)
+	}
+	if _, ok := report.mutes["unknown"]; ok {
+		t.Error("Expected user with failed move not to be muted.")
+	}
+
+	summary := report.Summary()
+	for _, s := range []string{"Muted: 2", "Un-muted: 1"} {
+		if !strings.Contains(summary, s) {
+			t.Errorf("Expected summary %q to contain %q", summary, s)
+		}
+	}
+
+	// Mute failures are reported.
+	plan = &movementPlan{
+		guild: "guild",
+		mutes: map[string]bool{"unknown": true},
+	}
+	report = plan.Execute(context.Background(), cfg, fm)
+	if report.Err() == nil {
+		t.Error("Expected report with failed mute to return an error.")
+	}
+	if summary := report.Summary(); !strings.Contains(summary, "Mute failed: <@unknown> (") {
+		t.Errorf("Expected summary %q to list failed mutes", summary)
+	}
+}
@@ -0,0 +1,108 @@
+package mover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// previewMoves builds the movement plan for the given phase without executing it and responds
+// with the planned moves.
+func (b *Bot) previewMoves(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, phase string) error {
+	var plan *movementPlan
+	var vs *discordVoiceState
+	var err error
+	switch phase {
+	case phaseNight:
+		plan, vs, err = b.buildNightPlan(ctx, s, i)
+	case phaseDay:
+		plan, vs, err = b.buildDayPlan(ctx, s, i)
+	default:
+		return fmt.Errorf("unknown phase %q", phase)
+	}
+	var content string
+	var cottagesErr *notEnoughCottagesError
+	switch {
+	case errors.As(err, &cottagesErr) && b.cfg.ForGuild(i.GuildID).AutoCreateCottages:
+		// Not an error, the moves are planned once the missing cottages exist.
+		content = fmt.Sprintf("**Preview of the %s phase:** %d cottage(s) will be created when the night starts, %d move(s) are planned then.",
+			phase, cottagesErr.missing(), cottagesErr.needed)
+	case err != nil:
+		return err
+	default:
+		content = renderPlanPreview(phase, plan, vs)
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:           discordgo.MessageFlagsEphemeral,
+			Content:         content,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}, discordgo.WithContext(ctx))
+}
+
+// memberName returns the display name of the member, falling back to the user name and ID.
+func memberName(member *discordgo.Member) string {
+	if name := member.DisplayName(); name != "" {
+		return name
+	}
+	if member.User.Username != "" {
+		return member.User.Username
+	}
+	return member.User.ID
+}
+
+// renderPlanPreview renders the plan using display names and channel names instead of IDs.
+func renderPlanPreview(phase string, plan *movementPlan, vs *discordVoiceState) string {
+	names := make(map[string]string)
+	for _, member := range vs.members {
+		names[member.User.ID] = memberName(member)
+	}
+
+	// Show channels in the same order as discord does.
+	channels := append([]*discordgo.Channel{vs.townSquare}, vs.cottages...)
+	occupants := make(map[string][]string)
+	for user, channel := range plan.moves {
+		occupants[channel] = append(occupants[channel], names[user])
+	}
+	for user, channel := range plan.inPlace {
+		occupants[channel] = append(occupants[channel], names[user]+" (already there)")
+	}
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("**Preview of the %s phase:** %d move(s), %d already in place.", phase, len(plan.moves), len(plan.inPlace)))
+	var emptyCottages int
+	for _, channel := range channels {
+		users := occupants[channel.ID]
+		if len(users) == 0 {
+			if channel != vs.townSquare {
+				emptyCottages++
+			}
+			continue
+		}
+		sort.Strings(users)
+		lines = append(lines, fmt.Sprintf("- %s: %s", channel.Name, strings.Join(users, ", ")))
+	}
+	if phase == phaseNight {
+		lines = append(lines, fmt.Sprintf("%d of %d cottage(s) left empty.", emptyCottages, len(vs.cottages)))
+	}
+	var muted, unmuted int
+	for _, mute := range plan.mutes {
+		if mute {
+			muted++
+		} else {
+			unmuted++
+		}
+	}
+	if muted+unmuted > 0 {
+		lines = append(lines, fmt.Sprintf("%d user(s) will be muted, %d un-muted.", muted, unmuted))
+	}
+
+	return strings.Join(lines, "\n")
+}
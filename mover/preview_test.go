@@ -0,0 +1,119 @@
+package mover
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPreviewMoves(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+
+	ctx := context.Background()
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+		},
+	}
+
+	for _, tc := range []struct {
+		phase string
+		want  []string
+	}{
+		{
+			phase: phaseNight,
+			want: []string{
+				"Preview of the night phase:** 5 move(s), 0 already in place.",
+				"User 1",
+				"Story Teller",
+				// Users without a display name are shown by ID.
+				"storyteller2",
+				"cottage",
+				"left empty",
+			},
+		},
+		{
+			phase: phaseDay,
+			want: []string{
+				"Preview of the day phase:** 4 move(s), 1 already in place.",
+				"townsquare: ",
+				"User 1 (already there)",
+				"User 2",
+			},
+		},
+	} {
+		d := &fakeDiscordSession{
+			id: "guild",
+		}
+		if err := b.previewMoves(ctx, d, i, tc.phase); err != nil {
+			t.Fatalf("Cannot preview %s moves: %v", tc.phase, err)
+		}
+
+		if len(d.responses) != 1 {
+			t.Fatalf("Expected exactly one response, got %d", len(d.responses))
+		}
+		got := d.responses[0].Data.Content
+		for _, want := range tc.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("Expected %s preview %q to contain %q", tc.phase, got, want)
+			}
+		}
+	}
+
+	// Previews must neither move anyone nor remember cottages.
+	select {
+	case plan := <-plans:
+		t.Fatalf("Expected no plan to be dispatched by a preview, got %v", plan)
+	default:
+	}
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if len(state.Cottages) != 0 || state.Phase != "" {
+		t.Fatalf("Expected preview to not change the game state, got %#v", state)
+	}
+}
+
+func TestPreviewMovesWithMissingCottages(t *testing.T) {
+	b, _ := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+		AutoCreateCottages:      true,
+	})
+	d := &fakeDiscordSession{id: "guild", deletedChannels: []string{"cottage4", "cottage5"}}
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+		},
+	}
+
+	// Missing cottages are created when the night starts, which is no reason to fail the preview.
+	if err := b.previewMoves(context.Background(), d, i, phaseNight); err != nil {
+		t.Fatalf("Cannot preview night moves: %v", err)
+	}
+	response := d.responses[len(d.responses)-1].Data
+	if want := "2 cottage(s) will be created when the night starts, 5 move(s) are planned then."; !strings.Contains(response.Content, want) {
+		t.Errorf("Expected preview %q to contain %q", response.Content, want)
+	}
+	if response.Flags != discordgo.MessageFlagsEphemeral {
+		t.Error("Expected the preview to be ephemeral.")
+	}
+}
@@ -0,0 +1,119 @@
+package mover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// configRef describes a configured channel or role for error messages.
+func configRef(id, name string) string {
+	if id != "" {
+		return fmt.Sprintf("with ID %s", id)
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+// findChannel returns the channel with the given ID. If no ID is configured, returns the first
+// channel with the given name instead.
+func findChannel(channels []*discordgo.Channel, id, name string) *discordgo.Channel {
+	for _, channel := range channels {
+		if id != "" && channel.ID == id || id == "" && channel.Name == name {
+			return channel
+		}
+	}
+	return nil
+}
+
+// findRole returns the ID of the role with the given ID. If no ID is configured, returns the ID of
+// the first role with the given name instead.
+func findRole(ctx context.Context, s discordSession, guildID, id, name string) (string, error) {
+	allRoles, err := s.GuildRoles(guildID, discordgo.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch guild roles: %w", err)
+	}
+
+	for _, role := range allRoles {
+		if id != "" && role.ID == id || id == "" && role.Name == name {
+			return role.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot find role %s", configRef(id, name))
+}
+
+// findStoryTellerRole returns the ID of the configured story teller role.
+func (b *Bot) findStoryTellerRole(ctx context.Context, s discordSession, guildID string) (string, error) {
+	cfg := b.cfg.ForGuild(guildID)
+	id, err := findRole(ctx, s, guildID, cfg.StoryTellerRoleID, cfg.StoryTellerRole)
+	if err != nil {
+		return "", fmt.Errorf("cannot find story teller role: %w", err)
+	}
+	return id, nil
+}
+
+// findSpectatorRole returns the ID of the configured spectator role, or an empty ID if no
+// spectator role is configured.
+func (b *Bot) findSpectatorRole(ctx context.Context, s discordSession, guildID string) (string, error) {
+	cfg := b.cfg.ForGuild(guildID)
+	if cfg.SpectatorRoleID == "" && cfg.SpectatorRole == "" {
+		return "", nil
+	}
+	id, err := findRole(ctx, s, guildID, cfg.SpectatorRoleID, cfg.SpectatorRole)
+	if err != nil {
+		return "", fmt.Errorf("cannot find spectator role: %w", err)
+	}
+	return id, nil
+}
+
+// validateGuildConfig checks that every configured channel and role ID exists in the guild and
+// that the channels have the expected type. Names are not checked, they are resolved on demand.
+func (b *Bot) validateGuildConfig(ctx context.Context, s discordSession, guildID string) error {
+	cfg := b.cfg.ForGuild(guildID)
+	channels, err := s.GuildChannels(guildID, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("cannot list guild channels: %w", err)
+	}
+	channelsByID := make(map[string]*discordgo.Channel)
+	for _, channel := range channels {
+		channelsByID[channel.ID] = channel
+	}
+
+	var errs []error
+	for _, c := range []struct {
+		desc     string
+		id       string
+		wantType discordgo.ChannelType
+	}{
+		{"night phase category", cfg.NightPhaseCategoryID, discordgo.ChannelTypeGuildCategory},
+		{"day phase category", cfg.DayPhaseCategoryID, discordgo.ChannelTypeGuildCategory},
+		{"town square", cfg.TownSquareID, discordgo.ChannelTypeGuildVoice},
+	} {
+		if c.id == "" {
+			continue
+		}
+		channel, ok := channelsByID[c.id]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s with ID %s does not exist", c.desc, c.id))
+			continue
+		}
+		if channel.Type != c.wantType {
+			errs = append(errs, fmt.Errorf("%s with ID %s has channel type %d, want %d", c.desc, c.id, channel.Type, c.wantType))
+		}
+	}
+
+	if cfg.StoryTellerRoleID != "" {
+		if _, err := b.findStoryTellerRole(ctx, s, guildID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.SpectatorRoleID != "" {
+		if _, err := b.findSpectatorRole(ctx, s, guildID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,150 @@
+package mover
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBuildDiscordVoiceStateByID(t *testing.T) {
+	// IDs take precedence over (outdated) names.
+	b := New(&Config{
+		Tokens:                  []string{"a"},
+		NightPhaseCategory:      "renamed night phase",
+		DayPhaseCategory:        "renamed day phase",
+		TownSquare:              "inn",
+		StoryTellerRole:         "renamed storyteller",
+		NightPhaseCategoryID:    "night phase",
+		DayPhaseCategoryID:      "day phase",
+		TownSquareID:            "townsquare",
+		StoryTellerRoleID:       "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	d := &fakeDiscordSession{
+		id: "guild",
+	}
+
+	ctx := context.Background()
+	vs, err := b.buildDiscordVoiceState(ctx, d, "guild")
+	if err != nil {
+		t.Fatalf("Cannot build voice state: %v", err)
+	}
+	if vs.townSquare.ID != "townsquare" {
+		t.Errorf("Expected Town Square to be resolved by ID, got %s", vs.townSquare.ID)
+	}
+	if len(vs.cottages) != 5 {
+		t.Errorf("Expected 5 cottages in the night category, got %d", len(vs.cottages))
+	}
+
+	role, err := b.findStoryTellerRole(ctx, d, "guild")
+	if err != nil {
+		t.Fatalf("Cannot find story teller role: %v", err)
+	}
+	if role != "storyteller" {
+		t.Errorf("Expected story teller role to be resolved by ID, got %s", role)
+	}
+}
+
+func TestValidateGuildConfig(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		cfg     *Config
+		wantErr bool
+	}{
+		{
+			desc: "names only",
+			cfg: &Config{
+				NightPhaseCategory: "does not exist",
+				DayPhaseCategory:   "day phase",
+				TownSquare:         "townsquare",
+				StoryTellerRole:    "storyteller",
+			},
+			wantErr: false,
+		},
+		{
+			desc: "valid IDs",
+			cfg: &Config{
+				NightPhaseCategoryID: "night phase",
+				DayPhaseCategoryID:   "day phase",
+				TownSquareID:         "townsquare",
+				StoryTellerRoleID:    "storyteller",
+			},
+			wantErr: false,
+		},
+		{
+			desc: "unknown channel ID",
+			cfg: &Config{
+				NightPhaseCategoryID: "night phase",
+				DayPhaseCategoryID:   "day phase",
+				TownSquareID:         "library",
+			},
+			wantErr: true,
+		},
+		{
+			desc: "voice channel as category",
+			cfg: &Config{
+				NightPhaseCategoryID: "cottage1",
+				DayPhaseCategoryID:   "day phase",
+				TownSquareID:         "townsquare",
+			},
+			wantErr: true,
+		},
+		{
+			desc: "category as town square",
+			cfg: &Config{
+				TownSquareID: "day phase",
+			},
+			wantErr: true,
+		},
+		{
+			desc: "unknown role ID",
+			cfg: &Config{
+				StoryTellerRoleID: "role4",
+			},
+			wantErr: true,
+		},
+	} {
+		b := New(tc.cfg)
+		d := &fakeDiscordSession{
+			id: "guild",
+		}
+		if err := b.validateGuildConfig(context.Background(), d, "guild"); (err != nil) != tc.wantErr {
+			t.Errorf("%s: validateGuildConfig() returned unexpected error %v, want error: %t", tc.desc, err, tc.wantErr)
+		}
+	}
+}
+
+func TestBuildDiscordVoiceStatePerGuild(t *testing.T) {
+	maxCottages := 2
+	b := New(&Config{
+		Tokens:                  []string{"a"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+		Guilds: map[string]*GuildConfig{
+			"guild": {
+				TownSquare:  "inn",
+				MaxCottages: &maxCottages,
+			},
+		},
+	})
+	d := &fakeDiscordSession{
+		id: "guild",
+	}
+
+	vs, err := b.buildDiscordVoiceState(context.Background(), d, "guild")
+	if err != nil {
+		t.Fatalf("Cannot build voice state: %v", err)
+	}
+	if vs.townSquare.ID != "inn" {
+		t.Errorf("Expected guild override for Town Square, got %s", vs.townSquare.ID)
+	}
+	if len(vs.cottages) != 2 {
+		t.Errorf("Expected guild to use only 2 cottages, got %d", len(vs.cottages))
+	}
+}
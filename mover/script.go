@@ -0,0 +1,211 @@
+package mover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// maxScriptSize is the maximum size of a script JSON file in bytes.
+const maxScriptSize = 64 << 10
+
+// scriptMetaID is the ID of the script entry that contains the name and author of the script.
+const scriptMetaID = "_meta"
+
+// teamTitles contains the plural titles of all character teams.
+var teamTitles = map[string]string{
+	teamTownsfolk: "Townsfolk",
+	teamOutsider:  "Outsiders",
+	teamMinion:    "Minions",
+	teamDemon:     "Demons",
+	teamTraveller: "Travellers",
+}
+
+// scriptEntry is a single entry of a script JSON file. The Script Tool writes objects with an ID,
+// older scripts only contain the IDs.
+type scriptEntry struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Author string `json:"author"`
+}
+
+func (e *scriptEntry) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &e.ID); err == nil {
+		return nil
+	}
+	type entry scriptEntry
+	return json.Unmarshal(data, (*entry)(e))
+}
+
+// parseScript parses and validates a script in the JSON format of the Script Tool and
+// clocktower.online, i.e. an array of character IDs with an optional meta entry.
+func parseScript(data []byte) (*Script, error) {
+	var entries []*scriptEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid script, expected an array of character IDs: %w", err)
+	}
+
+	script := &Script{}
+	var unknown []string
+	for _, entry := range entries {
+		if entry.ID == scriptMetaID {
+			script.Name, script.Author = entry.Name, entry.Author
+			continue
+		}
+		c := findCharacter(entry.ID)
+		switch {
+		case c == nil:
+			unknown = append(unknown, entry.ID)
+		case slices.Contains(script.Characters, c.id):
+			return nil, fmt.Errorf("invalid script, %s is listed more than once", c.name)
+		default:
+			script.Characters = append(script.Characters, c.id)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("invalid script, unknown character(s): %s", strings.Join(unknown, ", "))
+	}
+	if len(script.charactersOf(teamDemon)) == 0 {
+		return nil, fmt.Errorf("invalid script, it has no demon")
+	}
+	return script, nil
+}
+
+// charactersOf returns the characters of the script in the given team.
+func (s *Script) charactersOf(team string) []*character {
+	var found []*character
+	for _, id := range s.Characters {
+		if c := findCharacter(id); c != nil && c.team == team {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// outsiderModifiers returns the fewest and most Outsiders that characters on the script add to the
+// setup, and a description of every such character.
+func (s *Script) outsiderModifiers() (int, int, []string) {
+	var fewest, most int
+	var modifiers []string
+	for _, id := range s.Characters {
+		m, ok := outsiderModifiers[id]
+		if !ok {
+			continue
+		}
+		fewest, most = min(fewest, m.min), max(most, m.max)
+		modifiers = append(modifiers, fmt.Sprintf("%s (%s)", findCharacter(id).name, m.text))
+	}
+	return fewest, most, modifiers
+}
+
+// loadScript handles /game script. The attached script is validated and replaces the script of
+// the guild, which is kept across games. The script summary is posted in the channel.
+func (b *Bot) loadScript(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	data := i.ApplicationCommandData()
+	options := data.Options[0].Options
+	if len(options) == 0 || data.Resolved == nil {
+		return fmt.Errorf("missing script attachment")
+	}
+	attachment := data.Resolved.Attachments[options[0].Value.(string)]
+	if attachment == nil {
+		return fmt.Errorf("missing script attachment")
+	}
+	if attachment.Size > maxScriptSize {
+		return fmt.Errorf("script %s is too large, at most %d bytes are allowed", attachment.Filename, maxScriptSize)
+	}
+
+	content, err := s.Download(ctx, attachment.URL)
+	if err != nil {
+		return fmt.Errorf("cannot download script: %w", err)
+	}
+	script, err := parseScript(content)
+	if err != nil {
+		return err
+	}
+	if script.Name == "" {
+		script.Name = strings.TrimSuffix(attachment.Filename, ".json")
+	}
+
+	var state *GameState
+	if err := b.store.Update(i.GuildID, func(g *GameState) error {
+		g.Script = script
+		state = g.clone()
+		return nil
+	}); err != nil {
+		return fmt.Errorf("cannot store game state: %w", err)
+	}
+
+	log.Printf("Loaded script %q with %d characters in guild %s.", script.Name, len(script.Characters), i.GuildID)
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         renderScript(state),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}, discordgo.WithContext(ctx))
+}
+
+// renderScript renders the summary of the game's script: its characters by team and, if a game is
+// running, the setup and the vote threshold for the seated players.
+func renderScript(state *GameState) string {
+	script := state.Script
+	title := fmt.Sprintf("📜 **%s**", script.Name)
+	if script.Author != "" {
+		title += fmt.Sprintf(" by %s", script.Author)
+	}
+	lines := []string{title}
+
+	for _, team := range teams {
+		found := script.charactersOf(team)
+		if len(found) == 0 {
+			continue
+		}
+		var names []string
+		for _, c := range found {
+			names = append(names, c.name)
+		}
+		lines = append(lines, fmt.Sprintf("%s (%d): %s", teamTitles[team], len(found), strings.Join(names, ", ")))
+	}
+
+	if !state.Running {
+		return strings.Join(lines, "\n")
+	}
+
+	// Travellers do not take a character of the script.
+	players := len(state.Players) - len(state.Travellers)
+	counts, ok := setup(players)
+	if !ok {
+		lines = append(lines, fmt.Sprintf("⚠️ The script needs 5 to %d players, %d are seated.", 4+len(setupCounts), players))
+	} else {
+		// Characters on the script that modify the setup may need more Outsiders or Townsfolk.
+		fewest, most, modifiers := script.outsiderModifiers()
+		needed := map[string]int{
+			teamTownsfolk: counts[teamTownsfolk] - fewest,
+			teamOutsider:  counts[teamOutsider] + most,
+		}
+		var parts, warnings []string
+		for _, team := range teams[:4] {
+			parts = append(parts, fmt.Sprintf("%s %d", teamTitles[team], counts[team]))
+			n := max(counts[team], needed[team])
+			if available := len(script.charactersOf(team)); available < n {
+				warnings = append(warnings, fmt.Sprintf("⚠️ The script has %d %s, %d are needed.", available, teamTitles[team], n))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("Setup for %d players: %s.", players, strings.Join(parts, ", ")))
+		if len(modifiers) > 0 {
+			lines = append(lines, fmt.Sprintf("Setup modifiers: %s.", strings.Join(modifiers, ", ")))
+		}
+		lines = append(lines, warnings...)
+	}
+	lines = append(lines, fmt.Sprintf("%d vote(s) are needed to execute, %d player(s) alive.", state.voteThreshold(), state.living()))
+	if slices.Contains(script.Characters, voudon) {
+		lines = append(lines, "While the Voudon lives, only they and the dead vote, and no majority is needed.")
+	}
+
+	return strings.Join(lines, "\n")
+}
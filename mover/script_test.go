@@ -0,0 +1,179 @@
+package mover
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+// troubleBrewing is the Trouble Brewing script as exported by the Script Tool.
+const troubleBrewing = `[
+	{"id": "_meta", "name": "Trouble Brewing", "author": "The Pandemonium Institute"},
+	{"id": "washerwoman"}, {"id": "librarian"}, {"id": "investigator"}, {"id": "chef"},
+	{"id": "empath"}, {"id": "fortune_teller"}, {"id": "undertaker"}, {"id": "monk"},
+	{"id": "ravenkeeper"}, {"id": "virgin"}, {"id": "slayer"}, {"id": "soldier"}, {"id": "mayor"},
+	{"id": "butler"}, {"id": "drunk"}, {"id": "recluse"}, {"id": "saint"},
+	{"id": "poisoner"}, {"id": "spy"}, {"id": "scarlet_woman"}, {"id": "baron"},
+	{"id": "imp"}
+]`
+
+func scriptInteraction(url string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: slashCommandGame,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{
+						Name: gameCommandScript,
+						Type: discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandInteractionDataOption{
+							{Name: "script", Type: discordgo.ApplicationCommandOptionAttachment, Value: "attachment"},
+						},
+					},
+				},
+				Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+					Attachments: map[string]*discordgo.MessageAttachment{
+						"attachment": {ID: "attachment", URL: url, Filename: "custom.json", Size: 100},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		data    string
+		want    *Script
+		wantErr bool
+	}{
+		{
+			desc: "plain IDs",
+			data: `["imp", "fortuneteller", "Scarlet Woman"]`,
+			want: &Script{Characters: []string{"imp", "fortuneteller", "scarletwoman"}},
+		},
+		{
+			desc: "objects with meta",
+			data: `[{"id": "_meta", "name": "Tiny", "author": "Someone"}, {"id": "chef"}, {"id": "po"}]`,
+			want: &Script{Name: "Tiny", Author: "Someone", Characters: []string{"chef", "po"}},
+		},
+		{desc: "unknown character", data: `["imp", "nobody"]`, wantErr: true},
+		{desc: "duplicate character", data: `["imp", "chef", "chef"]`, wantErr: true},
+		{desc: "no demon", data: `["chef"]`, wantErr: true},
+		{desc: "not an array", data: `{"id": "imp"}`, wantErr: true},
+	} {
+		got, err := parseScript([]byte(tc.data))
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: parseScript() returned unexpected error %v, want error: %t", tc.desc, err, tc.wantErr)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("%s: Script mismatch (-want, +got):%s\n", tc.desc, diff)
+		}
+	}
+}
+
+func TestLoadScript(t *testing.T) {
+	b, _ := newTestBot(&Config{PerRequestSeconds: 5})
+	d := &fakeDiscordSession{
+		id: "guild",
+		downloads: map[string][]byte{
+			"https://cdn/tb.json":     []byte(troubleBrewing),
+			"https://cdn/broken.json": []byte(`["imp", "nobody"]`),
+		},
+	}
+	ctx := context.Background()
+
+	if err := b.loadScript(ctx, d, scriptInteraction("https://cdn/broken.json")); err == nil {
+		t.Error("Expected error for an invalid script, got nil")
+	}
+
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+		state.Travellers = []string{"8"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+	if err := b.loadScript(ctx, d, scriptInteraction("https://cdn/tb.json")); err != nil {
+		t.Fatalf("Cannot load script: %v", err)
+	}
+	got := d.responses[len(d.responses)-1].Data.Content
+	for _, want := range []string{
+		"📜 **Trouble Brewing** by The Pandemonium Institute",
+		"Outsiders (4): Butler, Drunk, Recluse, Saint",
+		"Demons (1): Imp",
+		"Setup for 7 players: Townsfolk 5, Outsiders 0, Minions 1, Demons 1.",
+		"Setup modifiers: Baron (+2 Outsiders).",
+		"4 vote(s) are needed to execute, 8 player(s) alive.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected script summary to contain %q, got %q", want, got)
+		}
+	}
+
+	// The script is kept for the next game.
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.reset()
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if state.Script == nil || state.Script.Name != "Trouble Brewing" || len(state.Script.Characters) != 22 {
+		t.Errorf("Expected the script to survive a new game, got %+v", state.Script)
+	}
+}
+
+func TestRenderScriptWarnings(t *testing.T) {
+	state := &GameState{
+		Running: true,
+		Players: []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"},
+		Script:  &Script{Name: "Tiny", Characters: []string{"chef", "empath", "imp", "poisoner"}},
+	}
+	got := renderScript(state)
+	if !strings.Contains(got, "⚠️ The script has 2 Townsfolk, 7 are needed.") || !strings.Contains(got, "⚠️ The script has 1 Minions, 2 are needed.") {
+		t.Errorf("Expected warnings about missing characters, got %q", got)
+	}
+
+	state.Players = state.Players[:4]
+	if got := renderScript(state); !strings.Contains(got, "⚠️ The script needs 5 to 15 players, 4 are seated.") {
+		t.Errorf("Expected warning about the player count, got %q", got)
+	}
+}
+
+func TestRenderScriptSetupModifiers(t *testing.T) {
+	// With the Baron, 9 players need up to 4 Outsiders, with the Godfather up to 6 Townsfolk.
+	state := &GameState{
+		Running: true,
+		Players: []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"},
+		Script: &Script{Name: "Modified", Characters: []string{
+			"chef", "empath", "librarian", "monk", "soldier", "butler", "drunk", "saint", "recluse",
+			"baron", "godfather", "imp", "voudon",
+		}},
+	}
+	got := renderScript(state)
+	for _, want := range []string{
+		"Setup for 9 players: Townsfolk 5, Outsiders 2, Minions 1, Demons 1.",
+		"Setup modifiers: Baron (+2 Outsiders), Godfather (-1 or +1 Outsider).",
+		"⚠️ The script has 5 Townsfolk, 6 are needed.",
+		"While the Voudon lives, only they and the dead vote, and no majority is needed.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected script summary to contain %q, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, "Outsiders, ") {
+		t.Errorf("Expected no warning about Outsiders, got %q", got)
+	}
+}
@@ -0,0 +1,197 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// Subcommands of the /seats slash command.
+const (
+	seatsCommandShow    = "show"
+	seatsCommandSet     = "set"
+	seatsCommandShuffle = "shuffle"
+)
+
+// joinTracker remembers when members joined Town Square. Used to seat players in the order they
+// joined when a new game starts.
+type joinTracker struct {
+	mu sync.Mutex
+	// joins maps guild IDs to user IDs to the time they joined Town Square.
+	joins map[string]map[string]time.Time
+}
+
+func newJoinTracker() *joinTracker {
+	return &joinTracker{joins: make(map[string]map[string]time.Time)}
+}
+
+// update records everyone who is in Town Square now but was not before, and forgets everyone who
+// left it.
+func (t *joinTracker) update(guildID string, inTownSquare []string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	joins := make(map[string]time.Time)
+	for _, user := range inTownSquare {
+		joined, ok := t.joins[guildID][user]
+		if !ok {
+			joined = now
+		}
+		joins[user] = joined
+	}
+	t.joins[guildID] = joins
+}
+
+// sort sorts the users by the time they joined Town Square. Users whose join was not observed,
+// e.g. because they joined before the bot started, come first in their original order.
+func (t *joinTracker) sort(guildID string, users []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	joins := t.joins[guildID]
+	sort.SliceStable(users, func(i, j int) bool {
+		return joins[users[i]].Before(joins[users[j]])
+	})
+}
+
+// trackTownSquareJoins updates the join times of Town Square from the guild's voice states.
+func (b *Bot) trackTownSquareJoins(s discordSession, guildID string, now time.Time) {
+	guild, err := s.StateGuild(guildID)
+	if err != nil {
+		log.Printf("Cannot fetch guild state for guild %s: %v", guildID, err)
+		return
+	}
+	cfg := b.cfg.ForGuild(guildID)
+	townSquare := findChannel(guild.Channels, cfg.TownSquareID, cfg.TownSquare)
+	if townSquare == nil {
+		return
+	}
+
+	var inTownSquare []string
+	for _, userVoiceState := range guild.VoiceStates {
+		if userVoiceState.ChannelID == townSquare.ID {
+			inTownSquare = append(inTownSquare, userVoiceState.UserID)
+		}
+	}
+	b.joins.update(guildID, inTownSquare, now)
+}
+
+// onSeatsCommand handles the /seats slash command group.
+func (b *Bot) onSeatsCommand(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return fmt.Errorf("missing /seats subcommand")
+	}
+
+	switch options[0].Name {
+	case seatsCommandShow:
+		return b.showSeats(ctx, s, i)
+	case seatsCommandSet:
+		if len(options[0].Options) == 0 {
+			return fmt.Errorf("missing seat order")
+		}
+		return b.setSeats(ctx, s, i, func(players []string) ([]string, error) {
+			return parseSeatOrder(options[0].Options[0].StringValue(), players)
+		})
+	case seatsCommandShuffle:
+		return b.setSeats(ctx, s, i, func(players []string) ([]string, error) {
+			rand.Shuffle(len(players), func(i, j int) {
+				players[i], players[j] = players[j], players[i]
+			})
+			return players, nil
+		})
+	}
+
+	return fmt.Errorf("unknown /seats subcommand: %s", options[0].Name)
+}
+
+// userMentionPattern matches user mentions such as <@1234> or <@!1234>.
+var userMentionPattern = regexp.MustCompile(`<@!?(\d+)>`)
+
+// parseSeatOrder parses the mentions of all seated players in clockwise order. Every seated
+// player has to be mentioned exactly once.
+func parseSeatOrder(value string, players []string) ([]string, error) {
+	var order []string
+	for _, match := range userMentionPattern.FindAllStringSubmatch(value, -1) {
+		user := match[1]
+		switch {
+		case !slices.Contains(players, user):
+			return nil, fmt.Errorf("<@%s> is not seated in the game", user)
+		case slices.Contains(order, user):
+			return nil, fmt.Errorf("<@%s> is mentioned more than once", user)
+		}
+		order = append(order, user)
+	}
+	for _, player := range players {
+		if !slices.Contains(order, player) {
+			return nil, fmt.Errorf("<@%s> is missing, mention all %d seated players", player, len(players))
+		}
+	}
+	return order, nil
+}
+
+// setSeats changes the seat order of the game to the one returned by reorder and shows the new
+// seat order.
+func (b *Bot) setSeats(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, reorder func(players []string) ([]string, error)) error {
+	if err := b.store.Update(i.GuildID, func(state *GameState) error {
+		if !state.Running {
+			return fmt.Errorf("no game is running")
+		}
+		players, err := reorder(slices.Clone(state.Players))
+		if err != nil {
+			return err
+		}
+		state.Players = players
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	log.Printf("Changed seat order of guild %s.", i.GuildID)
+	return b.showSeats(ctx, s, i)
+}
+
+// showSeats posts the seat order of the game in the channel.
+func (b *Bot) showSeats(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	state, err := b.store.Get(i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot load game state: %w", err)
+	}
+	if !state.Running {
+		return fmt.Errorf("no game is running")
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         renderSeats(state),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	}, discordgo.WithContext(ctx))
+}
+
+// renderSeats renders the seats of all players in clockwise order. Travellers and dead players are
+// marked.
+func renderSeats(state *GameState) string {
+	lines := []string{fmt.Sprintf("**Seats in clockwise order (%d):**", len(state.Players))}
+	for n, player := range state.Players {
+		line := fmt.Sprintf("%d. <@%s>", n+1, player)
+		if state.isTraveller(player) {
+			line += " (traveller)"
+		}
+		if !state.isAlive(player) {
+			line += " 💀"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
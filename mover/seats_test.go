@@ -0,0 +1,191 @@
+package mover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/exp/slices"
+)
+
+func seatsInteraction(subcommand, players string) *discordgo.InteractionCreate {
+	option := &discordgo.ApplicationCommandInteractionDataOption{
+		Name: subcommand,
+		Type: discordgo.ApplicationCommandOptionSubCommand,
+	}
+	if players != "" {
+		option.Options = []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "players", Type: discordgo.ApplicationCommandOptionString, Value: players},
+		}
+	}
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name:    slashCommandSeats,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{option},
+			},
+		},
+	}
+}
+
+func TestJoinTracker(t *testing.T) {
+	joins := newJoinTracker()
+	start := time.Now()
+
+	joins.update("guild", []string{"user1", "user2"}, start)
+	joins.update("guild", []string{"user1", "user2", "user3"}, start.Add(time.Second))
+	// user1 leaves and joins again, which moves them to the end.
+	joins.update("guild", []string{"user2", "user3"}, start.Add(2*time.Second))
+	joins.update("guild", []string{"user2", "user3", "user1"}, start.Add(3*time.Second))
+
+	// Nobody saw user4 join, they come first.
+	users := []string{"user1", "user2", "user3", "user4"}
+	joins.sort("guild", users)
+	if diff := cmp.Diff([]string{"user4", "user2", "user3", "user1"}, users); diff != "" {
+		t.Errorf("Seat order mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestTrackTownSquareJoins(t *testing.T) {
+	b, _ := newTestBot(&Config{TownSquare: "townsquare"})
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user2", ChannelID: "townsquare"},
+		},
+	}
+	start := time.Now()
+	b.trackTownSquareJoins(d, "guild", start)
+	d.voiceStates = append(d.voiceStates, &discordgo.VoiceState{UserID: "user1", ChannelID: "townsquare"})
+	b.trackTownSquareJoins(d, "guild", start.Add(time.Second))
+
+	users := []string{"user1", "user2"}
+	b.joins.sort("guild", users)
+	if diff := cmp.Diff([]string{"user2", "user1"}, users); diff != "" {
+		t.Errorf("Seat order mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestParseSeatOrder(t *testing.T) {
+	players := []string{"1", "2", "3"}
+	for _, tc := range []struct {
+		desc    string
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"all players", "<@3> <@1> <@!2>", []string{"3", "1", "2"}, false},
+		{"separated by commas", "<@2>,<@3>,<@1>", []string{"2", "3", "1"}, false},
+		{"missing player", "<@1> <@2>", nil, true},
+		{"duplicate player", "<@1> <@2> <@3> <@1>", nil, true},
+		{"not seated", "<@1> <@2> <@3> <@4>", nil, true},
+		{"no mentions", "1 2 3", nil, true},
+	} {
+		got, err := parseSeatOrder(tc.value, players)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: parseSeatOrder(%q) returned unexpected error %v, want error: %t", tc.desc, tc.value, err, tc.wantErr)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("%s: Seat order mismatch (-want, +got):%s\n", tc.desc, diff)
+		}
+	}
+}
+
+func TestSeatsCommand(t *testing.T) {
+	b, _ := newTestBot(&Config{PerRequestSeconds: 5})
+	d := &fakeDiscordSession{id: "guild"}
+	ctx := context.Background()
+
+	if err := b.onSeatsCommand(ctx, d, seatsInteraction(seatsCommandShow, "")); err == nil {
+		t.Error("Expected error without a running game, got nil")
+	}
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"101", "102", "103"}
+		state.Dead = []string{"101"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	if err := b.onSeatsCommand(ctx, d, seatsInteraction(seatsCommandSet, "<@101> <@102>")); err == nil {
+		t.Error("Expected error for incomplete seat order, got nil")
+	}
+	if err := b.onSeatsCommand(ctx, d, seatsInteraction(seatsCommandSet, "<@103> <@101> <@102>")); err != nil {
+		t.Fatalf("Cannot set seat order: %v", err)
+	}
+	want := "**Seats in clockwise order (3):**\n1. <@103>\n2. <@101> 💀\n3. <@102>"
+	if got := d.responses[len(d.responses)-1].Data.Content; got != want {
+		t.Errorf("Unexpected response %q, want %q", got, want)
+	}
+
+	if err := b.onSeatsCommand(ctx, d, seatsInteraction(seatsCommandShuffle, "")); err != nil {
+		t.Fatalf("Cannot shuffle seat order: %v", err)
+	}
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	players := slices.Clone(state.Players)
+	slices.Sort(players)
+	if diff := cmp.Diff([]string{"101", "102", "103"}, players); diff != "" {
+		t.Errorf("Shuffled players mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestPrepareNightMovesSeatCottages(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	d := &fakeDiscordSession{id: "guild"}
+
+	// The seat order wins over the cottage remembered from a previous night.
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"user3", "user1", "user2"}
+		state.Cottages = map[string]string{"user1": "cottage5"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}},
+		},
+	}
+	if err := b.prepareNightMoves(context.Background(), d, i); err != nil {
+		t.Fatalf("Cannot prepare night moves: %v", err)
+	}
+
+	var plan *movementPlan
+	select {
+	case plan = <-plans:
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	for user, want := range map[string]string{"user3": "cottage1", "user1": "cottage2", "user2": "cottage3"} {
+		if got := plan.moves[user]; got != want {
+			t.Errorf("Expected %s to move to %s, got %s", user, want, got)
+		}
+	}
+	// Story tellers take the next free cottage.
+	if got := plan.moves["storyteller"]; got != "cottage4" {
+		t.Errorf("Expected storyteller to move to cottage4, got %s", got)
+	}
+}
@@ -0,0 +1,219 @@
+package mover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Names of the channels and roles created by /setup if no names are configured.
+const (
+	defaultDayPhaseCategory   = "Day Phase"
+	defaultNightPhaseCategory = "Night Phase"
+	defaultTownSquare         = "Town Square"
+	defaultStoryTellerRole    = "Storyteller"
+)
+
+// defaultSetupCottages is the number of cottages created by /setup unless specified otherwise.
+const defaultSetupCottages = 15
+
+// setupTimeout is the deadline for /setup, which may need to create many channels.
+const setupTimeout = time.Minute
+
+// adminPermission is required to use admin-only slash commands.
+var adminPermission int64 = discordgo.PermissionAdministrator
+
+// orDefault returns value, or def if value is empty.
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// setup handles the /setup slash command. It acknowledges the interaction right away since
+// creating all channels may take a while, and reports the result by editing the response.
+func (b *Bot) setup(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}, discordgo.WithContext(ctx)); err != nil {
+		return fmt.Errorf("cannot acknowledge interaction: %w", err)
+	}
+
+	cottages := defaultSetupCottages
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		cottages = int(options[0].IntValue())
+	}
+
+	setupCtx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+	content, err := b.setupGuild(setupCtx, s, i.GuildID, cottages)
+	if err != nil {
+		log.Printf("Setup of guild %s failed: %v", i.GuildID, err)
+		content = fmt.Sprintf("Setup failed: %v", err)
+	}
+
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}, discordgo.WithContext(setupCtx)); err != nil {
+		return fmt.Errorf("cannot edit interaction response: %w", err)
+	}
+	return nil
+}
+
+// setupGuild creates the day and night categories, Town Square, the given number of cottages and
+// the story teller role unless they exist already. Cottages are private, only story tellers and
+// the bots can see them. Returns the config values for the guild.
+func (b *Bot) setupGuild(ctx context.Context, s discordSession, guildID string, cottages int) (string, error) {
+	cfg := b.cfg.ForGuild(guildID)
+	var created []string
+
+	roles, err := s.GuildRoles(guildID, discordgo.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch guild roles: %w", err)
+	}
+	var storyTellerRole *discordgo.Role
+	for _, role := range roles {
+		if cfg.StoryTellerRoleID != "" && role.ID == cfg.StoryTellerRoleID || cfg.StoryTellerRoleID == "" && role.Name == orDefault(cfg.StoryTellerRole, defaultStoryTellerRole) {
+			storyTellerRole = role
+			break
+		}
+	}
+	if storyTellerRole == nil {
+		if cfg.StoryTellerRoleID != "" {
+			return "", fmt.Errorf("cannot find story teller role %s", configRef(cfg.StoryTellerRoleID, ""))
+		}
+		mentionable := true
+		storyTellerRole, err = s.GuildRoleCreate(guildID, &discordgo.RoleParams{
+			Name:        orDefault(cfg.StoryTellerRole, defaultStoryTellerRole),
+			Mentionable: &mentionable,
+		}, discordgo.WithContext(ctx))
+		if err != nil {
+			return "", fmt.Errorf("cannot create story teller role: %w", err)
+		}
+		created = append(created, fmt.Sprintf("role %s", storyTellerRole.Name))
+	}
+
+	channels, err := s.GuildChannels(guildID, discordgo.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("cannot list guild channels: %w", err)
+	}
+
+	// findOrCreate returns the configured channel and creates it if it does not exist yet.
+	findOrCreate := func(desc, id, name string, data discordgo.GuildChannelCreateData) (*discordgo.Channel, error) {
+		if channel := findChannel(channels, id, name); channel != nil {
+			return channel, nil
+		}
+		if id != "" {
+			return nil, fmt.Errorf("cannot find %s %s", desc, configRef(id, name))
+		}
+		data.Name = name
+		channel, err := s.GuildChannelCreateComplex(guildID, data, discordgo.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("cannot create %s %q: %w", desc, name, err)
+		}
+		channels = append(channels, channel)
+		created = append(created, fmt.Sprintf("%s %s", desc, channel.Name))
+		return channel, nil
+	}
+
+	dayCategory, err := findOrCreate("day category", cfg.DayPhaseCategoryID, orDefault(cfg.DayPhaseCategory, defaultDayPhaseCategory), discordgo.GuildChannelCreateData{
+		Type: discordgo.ChannelTypeGuildCategory,
+	})
+	if err != nil {
+		return "", err
+	}
+	townSquare, err := findOrCreate("Town Square", cfg.TownSquareID, orDefault(cfg.TownSquare, defaultTownSquare), discordgo.GuildChannelCreateData{
+		Type:     discordgo.ChannelTypeGuildVoice,
+		ParentID: dayCategory.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	nightCategory, err := findOrCreate("night category", cfg.NightPhaseCategoryID, orDefault(cfg.NightPhaseCategory, defaultNightPhaseCategory), discordgo.GuildChannelCreateData{
+		Type:                 discordgo.ChannelTypeGuildCategory,
+		PermissionOverwrites: privateOverwrites(guildID, storyTellerRole.ID, b.botUserIDs()),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// New cottages copy the permissions of existing cottages, or those of the private night
+	// category.
+	if missing := cottages - len(cottagesOf(channels, nightCategory)); missing > 0 {
+		ids, err := createCottageChannels(ctx, s, guildID, cfg.CottageNameTemplate, channels, nightCategory, missing)
+		if len(ids) > 0 {
+			created = append(created, fmt.Sprintf("%d cottage(s)", len(ids)))
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	values, err := json.MarshalIndent(struct {
+		NightPhaseCategory   string
+		NightPhaseCategoryID string
+		DayPhaseCategory     string
+		DayPhaseCategoryID   string
+		TownSquare           string
+		TownSquareID         string
+		StoryTellerRole      string
+		StoryTellerRoleID    string
+	}{
+		NightPhaseCategory:   nightCategory.Name,
+		NightPhaseCategoryID: nightCategory.ID,
+		DayPhaseCategory:     dayCategory.Name,
+		DayPhaseCategoryID:   dayCategory.ID,
+		TownSquare:           townSquare.Name,
+		TownSquareID:         townSquare.ID,
+		StoryTellerRole:      storyTellerRole.Name,
+		StoryTellerRoleID:    storyTellerRole.ID,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal config values: %w", err)
+	}
+
+	summary := "Everything already exists, nothing was created."
+	if len(created) > 0 {
+		summary = fmt.Sprintf("Created %s.", strings.Join(created, ", "))
+	}
+	log.Printf("Set up guild %s: %s", guildID, summary)
+
+	return fmt.Sprintf("**Server setup complete.** %s\nConfig values for this server:\n```json\n%s\n```", summary, values), nil
+}
+
+// privateOverwrites returns the permission overwrites of private night phase channels. Only story
+// tellers and the bots can see and join them.
+func privateOverwrites(guildID, storyTellerRoleID string, botUserIDs []string) []*discordgo.PermissionOverwrite {
+	const access = discordgo.PermissionViewChannel | discordgo.PermissionVoiceConnect
+	overwrites := []*discordgo.PermissionOverwrite{
+		// The @everyone role has the same ID as the guild.
+		{ID: guildID, Type: discordgo.PermissionOverwriteTypeRole, Deny: access},
+		{ID: storyTellerRoleID, Type: discordgo.PermissionOverwriteTypeRole, Allow: access},
+	}
+	for _, id := range botUserIDs {
+		overwrites = append(overwrites, &discordgo.PermissionOverwrite{
+			ID:    id,
+			Type:  discordgo.PermissionOverwriteTypeMember,
+			Allow: access | discordgo.PermissionVoiceMoveMembers,
+		})
+	}
+	return overwrites
+}
+
+// botUserIDs returns the user IDs of all bot sessions.
+func (b *Bot) botUserIDs() []string {
+	var ids []string
+	for _, session := range b.sessions {
+		if session.State != nil && session.State.User != nil {
+			ids = append(ids, session.State.User.ID)
+		}
+	}
+	return ids
+}
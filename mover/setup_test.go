@@ -0,0 +1,153 @@
+package mover
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetupGuild(t *testing.T) {
+	b, _ := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "Night",
+		DayPhaseCategory:        "Day",
+		StoryTellerRole:         "ST",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	d := &fakeDiscordSession{id: "guild"}
+	ctx := context.Background()
+
+	got, err := b.setupGuild(ctx, d, "guild", 2)
+	if err != nil {
+		t.Fatalf("Cannot set up guild: %v", err)
+	}
+
+	private := []*discordgo.PermissionOverwrite{
+		{ID: "guild", Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionViewChannel | discordgo.PermissionVoiceConnect},
+		{ID: "createdRole1", Type: discordgo.PermissionOverwriteTypeRole, Allow: discordgo.PermissionViewChannel | discordgo.PermissionVoiceConnect},
+	}
+	wantChannels := []discordgo.GuildChannelCreateData{
+		{Name: "Day", Type: discordgo.ChannelTypeGuildCategory},
+		{Name: "Town Square", Type: discordgo.ChannelTypeGuildVoice, ParentID: "created1"},
+		{Name: "Night", Type: discordgo.ChannelTypeGuildCategory, PermissionOverwrites: private},
+		{Name: "Cottage 1", Type: discordgo.ChannelTypeGuildVoice, ParentID: "created3", Position: 1, PermissionOverwrites: private},
+		{Name: "Cottage 2", Type: discordgo.ChannelTypeGuildVoice, ParentID: "created3", Position: 2, PermissionOverwrites: private},
+	}
+	if diff := cmp.Diff(wantChannels, d.createdChannels); diff != "" {
+		t.Errorf("Created channels mismatch (-want, +got):%s\n", diff)
+	}
+	if len(d.createdRoles) != 1 || d.createdRoles[0].Name != "ST" {
+		t.Errorf("Expected story teller role ST to be created, got %v", d.createdRoles)
+	}
+	for _, want := range []string{
+		"Created role ST, day category Day, Town Square Town Square, night category Night, 2 cottage(s).",
+		`"NightPhaseCategoryID": "created3"`,
+		`"TownSquare": "Town Square"`,
+		`"StoryTellerRoleID": "createdRole1"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected setup result %q to contain %q", got, want)
+		}
+	}
+
+	// Setup is idempotent.
+	got, err = b.setupGuild(ctx, d, "guild", 2)
+	if err != nil {
+		t.Fatalf("Cannot set up guild again: %v", err)
+	}
+	if want := "Everything already exists"; !strings.Contains(got, want) {
+		t.Errorf("Expected setup result %q to contain %q", got, want)
+	}
+	if len(d.createdChannels) != len(wantChannels) || len(d.createdRoles) != 1 {
+		t.Errorf("Expected nothing to be created again, got %d channels and %d roles", len(d.createdChannels), len(d.createdRoles))
+	}
+}
+
+func TestSetupGuildExistingLayout(t *testing.T) {
+	b, _ := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	d := &fakeDiscordSession{id: "guild"}
+
+	// Only the missing cottage is created, it copies the permissions of the existing cottages.
+	if _, err := b.setupGuild(context.Background(), d, "guild", 6); err != nil {
+		t.Fatalf("Cannot set up guild: %v", err)
+	}
+	want := []discordgo.GuildChannelCreateData{
+		{Name: "Cottage 6", Type: discordgo.ChannelTypeGuildVoice, ParentID: "night phase", Position: 6, PermissionOverwrites: privateCottage},
+	}
+	if diff := cmp.Diff(want, d.createdChannels); diff != "" {
+		t.Errorf("Created channels mismatch (-want, +got):%s\n", diff)
+	}
+
+	// Channels configured by ID are never created.
+	b.cfg.TownSquareID = "unknown"
+	if _, err := b.setupGuild(context.Background(), d, "guild", 6); err == nil {
+		t.Error("Expected error for unknown Town Square ID, got nil")
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	b, _ := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	d := &fakeDiscordSession{id: "guild"}
+
+	player := &discordgo.Member{User: &discordgo.User{ID: "user1"}, Roles: []string{"role1"}}
+	storyTeller := &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}}
+	admin := &discordgo.Member{User: &discordgo.User{ID: "admin"}, Permissions: discordgo.PermissionAdministrator}
+
+	for _, tc := range []struct {
+		desc   string
+		member *discordgo.Member
+		typ    discordgo.InteractionType
+		// command is the slash command or the custom ID of the button.
+		command string
+		wantErr bool
+	}{
+		{"story teller command by story teller", storyTeller, discordgo.InteractionApplicationCommand, slashCommandButtons, false},
+		{"story teller command by player", player, discordgo.InteractionApplicationCommand, slashCommandButtons, true},
+		{"button by player", player, discordgo.InteractionMessageComponent, buttonNight, true},
+		{"vote button by player", player, discordgo.InteractionMessageComponent, nominationButtonID(buttonVote, 0, 1), false},
+		{"count votes button by player", player, discordgo.InteractionMessageComponent, nominationButtonID(buttonCountVotes, 0, 1), true},
+		{"player command by player", player, discordgo.InteractionApplicationCommand, slashCommandWhisper, false},
+		{"admin command by admin", admin, discordgo.InteractionApplicationCommand, slashCommandSetup, false},
+		{"admin command by story teller", storyTeller, discordgo.InteractionApplicationCommand, slashCommandSetup, true},
+		{"outside of guild", nil, discordgo.InteractionApplicationCommand, slashCommandWhisper, true},
+	} {
+		i := &discordgo.InteractionCreate{
+			Interaction: &discordgo.Interaction{
+				Type:    tc.typ,
+				GuildID: "guild",
+				Member:  tc.member,
+			},
+		}
+		if tc.typ == discordgo.InteractionApplicationCommand {
+			i.Data = discordgo.ApplicationCommandInteractionData{Name: tc.command}
+		} else {
+			i.Data = discordgo.MessageComponentInteractionData{CustomID: tc.command}
+		}
+		if err := b.checkAccess(context.Background(), d, i); (err != nil) != tc.wantErr {
+			t.Errorf("%s: checkAccess() returned unexpected error %v, want error: %t", tc.desc, err, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package mover
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// errUnchanged is returned by game state updates that do not need to modify the state.
+var errUnchanged = errors.New("game state unchanged")
+
+// trackSideRooms updates the whisper log of the guild after a voice state change. Every side room
+// of the day category, i.e. every voice channel except Town Square, that holds at least two seated
+// players is logged as a conversation until fewer than two of them are left.
+func (b *Bot) trackSideRooms(s discordSession, guildID string, now time.Time) {
+	state, err := b.store.Get(guildID)
+	if err != nil {
+		log.Printf("Cannot load game state for guild %s: %v", guildID, err)
+		return
+	}
+	if !state.Running {
+		return // Conversations are only logged during games.
+	}
+
+	guild, err := s.StateGuild(guildID)
+	if err != nil {
+		log.Printf("Cannot fetch guild state for guild %s: %v", guildID, err)
+		return
+	}
+	cfg := b.cfg.ForGuild(guildID)
+	dayCategory := findChannel(guild.Channels, cfg.DayPhaseCategoryID, cfg.DayPhaseCategory)
+	townSquare := findChannel(guild.Channels, cfg.TownSquareID, cfg.TownSquare)
+	if dayCategory == nil || townSquare == nil {
+		return
+	}
+
+	sideRooms := make(map[string]bool)
+	for _, channel := range guild.Channels {
+		if channel.Type == discordgo.ChannelTypeGuildVoice && channel.ParentID == dayCategory.ID && channel.ID != townSquare.ID &&
+			!slices.Contains(cfg.IgnoredChannels, channel.ID) && !slices.Contains(cfg.IgnoredChannels, channel.Name) {
+			sideRooms[channel.ID] = true
+		}
+	}
+	occupants := make(map[string][]string)
+	for _, userVoiceState := range guild.VoiceStates {
+		if sideRooms[userVoiceState.ChannelID] && slices.Contains(state.Players, userVoiceState.UserID) {
+			occupants[userVoiceState.ChannelID] = append(occupants[userVoiceState.ChannelID], userVoiceState.UserID)
+		}
+	}
+
+	err = b.store.Update(guildID, func(state *GameState) error {
+		if !state.Running {
+			return errUnchanged
+		}
+		return logConversations(state, occupants, now)
+	})
+	if err != nil && !errors.Is(err, errUnchanged) {
+		log.Printf("Cannot update whisper log for guild %s: %v", guildID, err)
+	}
+}
+
+// logConversations updates the whisper log with the seated players currently in every side room.
+// Returns errUnchanged if the log is unchanged.
+func logConversations(state *GameState, occupants map[string][]string, now time.Time) error {
+	changed := false
+	ongoing := make(map[string]bool)
+	for _, entry := range state.Whispers {
+		if !entry.Ended.IsZero() {
+			continue
+		}
+		present := occupants[entry.Channel]
+		if len(present) < 2 {
+			entry.Ended = now
+			changed = true
+			continue
+		}
+		ongoing[entry.Channel] = true
+		for _, user := range present {
+			if !slices.Contains(entry.Participants, user) {
+				entry.Participants = append(entry.Participants, user)
+				changed = true
+			}
+		}
+	}
+
+	var channels []string
+	for channel, present := range occupants {
+		if len(present) >= 2 && !ongoing[channel] {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		participants := slices.Clone(occupants[channel])
+		sort.Strings(participants)
+		state.Whispers = append(state.Whispers, &WhisperLogEntry{
+			Day:          state.Day,
+			Channel:      channel,
+			Participants: participants,
+			Started:      now,
+		})
+		changed = true
+	}
+
+	if !changed {
+		return errUnchanged
+	}
+	return nil
+}
+
+// whispersOnDay returns the number of logged conversations of the user on the given day.
+func (g *GameState) whispersOnDay(user string, day int) int {
+	n := 0
+	for _, entry := range g.Whispers {
+		if entry.Day == day && slices.Contains(entry.Participants, user) {
+			n++
+		}
+	}
+	return n
+}
+
+// checkWhisperLimits returns an error iff the whisper of the participants exceeds the configured
+// whisper limits.
+func checkWhisperLimits(cfg *Config, state *GameState, participants []string) error {
+	if cfg.MaxWhisperParticipants > 0 && len(participants) > cfg.MaxWhisperParticipants {
+		return fmt.Errorf("whispers are limited to %d players", cfg.MaxWhisperParticipants)
+	}
+	if cfg.MaxWhispersPerDay > 0 {
+		for _, user := range participants {
+			if state.whispersOnDay(user, state.Day) >= cfg.MaxWhispersPerDay {
+				return fmt.Errorf("<@%s> already had %d whisper(s) today", user, cfg.MaxWhispersPerDay)
+			}
+		}
+	}
+	return nil
+}
+
+// renderWhisperLog renders all logged conversations of the given day.
+func renderWhisperLog(state *GameState, day int, now time.Time) string {
+	var lines []string
+	for _, entry := range state.Whispers {
+		if entry.Day != day {
+			continue
+		}
+		line := fmt.Sprintf("%s <#%s>: %s", entry.Started.UTC().Format("15:04:05"), entry.Channel, mentions(entry.Participants))
+		if entry.Ended.IsZero() {
+			line += fmt.Sprintf(", ongoing for %s", now.Sub(entry.Started).Round(time.Second))
+		} else {
+			line += fmt.Sprintf(", for %s", entry.Ended.Sub(entry.Started).Round(time.Second))
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return fmt.Sprintf("No whispers on day %d.", day)
+	}
+	return strings.Join(append([]string{fmt.Sprintf("**Whispers on day %d (%d):**", day, len(lines))}, lines...), "\n")
+}
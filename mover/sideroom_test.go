@@ -0,0 +1,161 @@
+package mover
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTrackSideRooms(t *testing.T) {
+	b, _ := newWhisperTestBot()
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"user1", "user2", "user3"}
+		state.Day = 2
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+	d := &fakeDiscordSession{id: "guild"}
+	start := time.Unix(1000, 0)
+
+	for n, step := range []struct {
+		voiceStates []*discordgo.VoiceState
+		want        []*WhisperLogEntry
+	}{
+		{
+			// A single player in a side room is not a conversation, neither is a story teller
+			// joining them.
+			voiceStates: []*discordgo.VoiceState{
+				{UserID: "user1", ChannelID: "inn"},
+				{UserID: "storyteller", ChannelID: "inn"},
+				{UserID: "user2", ChannelID: "townsquare"},
+				{UserID: "user3", ChannelID: "townsquare"},
+			},
+		},
+		{
+			voiceStates: []*discordgo.VoiceState{
+				{UserID: "user1", ChannelID: "inn"},
+				{UserID: "storyteller", ChannelID: "inn"},
+				{UserID: "user2", ChannelID: "inn"},
+				{UserID: "user3", ChannelID: "townsquare"},
+			},
+			want: []*WhisperLogEntry{
+				{Day: 2, Channel: "inn", Participants: []string{"user1", "user2"}, Started: start.Add(2 * time.Minute)},
+			},
+		},
+		{
+			// Players joining an ongoing conversation take part in it.
+			voiceStates: []*discordgo.VoiceState{
+				{UserID: "user1", ChannelID: "inn"},
+				{UserID: "user2", ChannelID: "inn"},
+				{UserID: "user3", ChannelID: "inn"},
+			},
+			want: []*WhisperLogEntry{
+				{Day: 2, Channel: "inn", Participants: []string{"user1", "user2", "user3"}, Started: start.Add(2 * time.Minute)},
+			},
+		},
+		{
+			// Town Square is not a side room.
+			voiceStates: []*discordgo.VoiceState{
+				{UserID: "user1", ChannelID: "inn"},
+				{UserID: "user2", ChannelID: "townsquare"},
+				{UserID: "user3", ChannelID: "townsquare"},
+			},
+			want: []*WhisperLogEntry{
+				{Day: 2, Channel: "inn", Participants: []string{"user1", "user2", "user3"}, Started: start.Add(2 * time.Minute), Ended: start.Add(4 * time.Minute)},
+			},
+		},
+	} {
+		d.voiceStates = step.voiceStates
+		b.trackSideRooms(d, "guild", start.Add(time.Duration(n+1)*time.Minute))
+
+		state, err := b.store.Get("guild")
+		if err != nil {
+			t.Fatalf("Cannot load game state: %v", err)
+		}
+		if diff := cmp.Diff(step.want, state.Whispers); diff != "" {
+			t.Errorf("Step %d: Whisper log mismatch (-want, +got):%s\n", n, diff)
+		}
+	}
+
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	got := renderWhisperLog(state, 2, start.Add(5*time.Minute))
+	for _, want := range []string{"Whispers on day 2 (1)", "<#inn>: <@user1>, <@user2>, <@user3>, for 2m0s"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected whisper log %q to contain %q", got, want)
+		}
+	}
+}
+
+func TestTrackSideRoomsWithoutGame(t *testing.T) {
+	b, _ := newWhisperTestBot()
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user1", ChannelID: "inn"},
+			{UserID: "user2", ChannelID: "inn"},
+		},
+	}
+
+	b.trackSideRooms(d, "guild", time.Now())
+
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if len(state.Whispers) != 0 {
+		t.Errorf("Expected no whispers to be logged without a game, got %d", len(state.Whispers))
+	}
+}
+
+func TestCheckWhisperLimits(t *testing.T) {
+	state := &GameState{
+		Day: 2,
+		Whispers: []*WhisperLogEntry{
+			{Day: 1, Participants: []string{"user1", "user2"}},
+			{Day: 2, Participants: []string{"user1", "user3"}},
+			{Day: 2, Participants: []string{"user1", "user2"}},
+		},
+	}
+
+	for _, tc := range []struct {
+		desc         string
+		cfg          *Config
+		participants []string
+		wantErr      bool
+	}{
+		{
+			desc:         "no limits",
+			cfg:          &Config{},
+			participants: []string{"user1", "user2", "user3", "user4"},
+		},
+		{
+			desc:         "too many participants",
+			cfg:          &Config{MaxWhisperParticipants: 3},
+			participants: []string{"user1", "user2", "user3", "user4"},
+			wantErr:      true,
+		},
+		{
+			desc:         "within daily limit",
+			cfg:          &Config{MaxWhispersPerDay: 2},
+			participants: []string{"user2", "user3"},
+		},
+		{
+			desc:         "daily limit reached",
+			cfg:          &Config{MaxWhispersPerDay: 2},
+			participants: []string{"user4", "user1"},
+			wantErr:      true,
+		},
+	} {
+		if err := checkWhisperLimits(tc.cfg, state, tc.participants); (err != nil) != tc.wantErr {
+			t.Errorf("%s: checkWhisperLimits() returned unexpected error %v, want error: %t", tc.desc, err, tc.wantErr)
+		}
+	}
+}
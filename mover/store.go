@@ -0,0 +1,294 @@
+package mover
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/maps"
+)
+
+// Game phases.
+const (
+	phaseNight = "night"
+	phaseDay   = "day"
+)
+
+// GameState is the persistent state of the game in a single guild.
+type GameState struct {
+	// Running is true iff a game has been started with /game start.
+	Running bool
+	// Game identifies the game started with /game start by its start time in unix nanoseconds.
+	// Vote messages contain it, so that their buttons cannot affect later games.
+	Game int64
+	// Phase is the current phase of the game, either "night" or "day". Empty before the first
+	// movement of the game.
+	Phase string
+	// Day is the current day number. Night N is followed by day N.
+	Day int
+	// Players contains the user IDs of all seated players in clockwise seat order.
+	Players []string
+	// Travellers contains the user IDs of all seated players who joined the game as travellers.
+	Travellers []string
+	// Dead contains the user IDs of all dead players.
+	Dead []string
+	// Nicknames maps the user IDs of all players whose nickname has been changed by the bot to
+	// their original nickname, which is empty if they had none.
+	Nicknames map[string]string
+	// GhostVotesUsed contains the user IDs of all dead players who have used their ghost vote.
+	GhostVotesUsed []string
+	// Grimoire maps the user IDs of seated players to their character, alignment and reminders.
+	Grimoire map[string]*GrimoireEntry
+	// NightVisits contains the user IDs of all players the story teller has visited in night order
+	// during the current night.
+	NightVisits []string
+	// Nominations contains all nominations of the game. The last one is still open for votes
+	// unless it has been counted.
+	Nominations []*Nomination
+	// Cottages maps user IDs to the cottage channel IDs they used during the last night.
+	Cottages map[string]string
+	// Whispers logs all conversations of seated players in side rooms of the day category.
+	Whispers []*WhisperLogEntry
+	// CreatedCottages contains the channel IDs of all cottages created by the bot. They are kept
+	// across games until they are cleaned up.
+	CreatedCottages []string
+	// Script is the script loaded with /game script, if any. It is kept across games.
+	Script *Script
+}
+
+// Script is a set of characters to play with.
+type Script struct {
+	Name   string
+	Author string
+	// Characters contains the IDs of all characters of the script in script order.
+	Characters []string
+}
+
+// WhisperLogEntry records a conversation of at least two seated players in a side room.
+type WhisperLogEntry struct {
+	Day     int
+	Channel string
+	// Participants contains the user IDs of everyone who took part in the conversation.
+	Participants []string
+	Started      time.Time
+	// Ended is zero while the conversation is ongoing.
+	Ended time.Time
+}
+
+// GrimoireEntry is the character of a player as recorded by the story teller.
+type GrimoireEntry struct {
+	// Character is the ID of the character, empty if none has been assigned.
+	Character string
+	// Alignment is either "good" or "evil".
+	Alignment string
+	// Reminders contains the reminder tokens of the player.
+	Reminders []string
+}
+
+// Nomination is the nomination of a player for execution along with its vote.
+type Nomination struct {
+	Day       int
+	Nominator string
+	Nominee   string
+	// Hands contains the user IDs of all players currently raising their hand.
+	Hands []string
+	// Counted is true once the votes have been counted.
+	Counted bool
+	// Voters contains the user IDs of all counted votes in clockwise order, starting after the
+	// nominee.
+	Voters []string
+	// Threshold is the number of votes the nominee needed when the votes were counted.
+	Threshold int
+}
+
+// clone returns a deep copy of the state.
+func (g *GameState) clone() *GameState {
+	data, err := json.Marshal(g)
+	if err != nil {
+		panic(fmt.Sprintf("cannot marshal game state: %v", err))
+	}
+	c := &GameState{}
+	if err := json.Unmarshal(data, c); err != nil {
+		panic(fmt.Sprintf("cannot unmarshal game state: %v", err))
+	}
+	return c
+}
+
+// reset forgets all state of the game except for the cottages created by the bot and the script.
+func (g *GameState) reset() {
+	*g = GameState{CreatedCottages: g.CreatedCottages, Script: g.Script}
+}
+
+// enterPhase advances the game to the given phase. Entering the day phase starts a new day,
+// entering the night phase starts a new night order.
+func (g *GameState) enterPhase(phase string) {
+	if phase == phaseDay && g.Phase != phaseDay {
+		g.Day++
+	}
+	if phase == phaseNight && g.Phase != phaseNight {
+		g.NightVisits = nil
+	}
+	g.Phase = phase
+}
+
+// GameStore persists the game state of every guild.
+type GameStore interface {
+	// Get returns a copy of the game state of the guild. Guilds without a game have an empty state.
+	Get(guildID string) (*GameState, error)
+	// Update atomically modifies the game state of the guild. The state is not modified if update
+	// returns an error.
+	Update(guildID string, update func(state *GameState) error) error
+	// Delete removes the game state of the guild, e.g. when a new game starts.
+	Delete(guildID string) error
+}
+
+// memoryGameStore keeps all game states in memory. Games are lost when the bot restarts.
+type memoryGameStore struct {
+	mu    sync.Mutex
+	games map[string]*GameState
+}
+
+// newMemoryGameStore creates a new empty in-memory game store.
+func newMemoryGameStore() *memoryGameStore {
+	return &memoryGameStore{games: make(map[string]*GameState)}
+}
+
+func (m *memoryGameStore) Get(guildID string) (*GameState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.games[guildID]; ok {
+		return state.clone(), nil
+	}
+	return &GameState{}, nil
+}
+
+func (m *memoryGameStore) Update(guildID string, update func(state *GameState) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, err := m.updatedLocked(guildID, update)
+	if err != nil {
+		return err
+	}
+	m.games[guildID] = state
+	return nil
+}
+
+// updatedLocked returns a copy of the game state of the guild with the update applied. The stored
+// state is not modified. Requires m.mu to be held.
+func (m *memoryGameStore) updatedLocked(guildID string, update func(state *GameState) error) (*GameState, error) {
+	state := &GameState{}
+	if s, ok := m.games[guildID]; ok {
+		state = s.clone()
+	}
+	if err := update(state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (m *memoryGameStore) Delete(guildID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.games, guildID)
+	return nil
+}
+
+// fileGameStore keeps all game states in memory and writes them to a JSON file after every
+// change, so that games survive bot restarts.
+type fileGameStore struct {
+	memoryGameStore
+	path string
+}
+
+// newFileGameStore creates a game store backed by the JSON file at path. Existing games are
+// loaded from the file if it exists.
+func newFileGameStore(path string) (*fileGameStore, error) {
+	f := &fileGameStore{
+		memoryGameStore: memoryGameStore{games: make(map[string]*GameState)},
+		path:            path,
+	}
+
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read game state file %q: %w", path, err)
+	}
+	if err := json.Unmarshal(contents, &f.games); err != nil {
+		return nil, fmt.Errorf("cannot parse game state file %q: %w", path, err)
+	}
+	if f.games == nil {
+		f.games = make(map[string]*GameState)
+	}
+
+	return f, nil
+}
+
+func (f *fileGameStore) Update(guildID string, update func(state *GameState) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state, err := f.updatedLocked(guildID, update)
+	if err != nil {
+		return err
+	}
+
+	// Only keep the new state once it has been written, so that the state in memory never differs
+	// from the state after a restart.
+	games := maps.Clone(f.games)
+	games[guildID] = state
+	if err := saveGames(f.path, games); err != nil {
+		return err
+	}
+	f.games = games
+	return nil
+}
+
+func (f *fileGameStore) Delete(guildID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	games := maps.Clone(f.games)
+	delete(games, guildID)
+	if err := saveGames(f.path, games); err != nil {
+		return err
+	}
+	f.games = games
+	return nil
+}
+
+// saveGames writes all games to the file at path. The file is replaced atomically so that a crash
+// never leaves a partially written file behind.
+func saveGames(path string, games map[string]*GameState) error {
+	contents, err := json.MarshalIndent(games, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal game states: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary game state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write game state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write game state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot replace game state file %q: %w", path, err)
+	}
+
+	return nil
+}
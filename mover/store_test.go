@@ -0,0 +1,164 @@
+package mover
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testGameStore(t *testing.T, store GameStore) {
+	t.Helper()
+
+	state, err := store.Get("guild1")
+	if err != nil {
+		t.Fatalf("Cannot get game state: %v", err)
+	}
+	if diff := cmp.Diff(&GameState{}, state); diff != "" {
+		t.Fatalf("Expected empty state for unknown guild (-want, +got):%s\n", diff)
+	}
+
+	if err := store.Update("guild1", func(state *GameState) error {
+		state.enterPhase(phaseNight)
+		state.Players = []string{"user1", "user2"}
+		state.Cottages = map[string]string{"user1": "cottage1", "user2": "cottage2"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+	if err := store.Update("guild2", func(state *GameState) error {
+		state.enterPhase(phaseDay)
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	// Failed updates must not change the state.
+	if err := store.Update("guild1", func(state *GameState) error {
+		state.Players = nil
+		return fmt.Errorf("failed")
+	}); err == nil {
+		t.Fatal("Expected failed update to return an error.")
+	}
+
+	want := &GameState{
+		Phase:    phaseNight,
+		Players:  []string{"user1", "user2"},
+		Cottages: map[string]string{"user1": "cottage1", "user2": "cottage2"},
+	}
+	state, err = store.Get("guild1")
+	if err != nil {
+		t.Fatalf("Cannot get game state: %v", err)
+	}
+	if diff := cmp.Diff(want, state); diff != "" {
+		t.Fatalf("Unexpected game state (-want, +got):%s\n", diff)
+	}
+
+	// Modifying the returned copy must not change the stored state.
+	state.Players[0] = "user3"
+	state.Cottages["user1"] = "cottage3"
+	if state, _ := store.Get("guild1"); !cmp.Equal(want, state) {
+		t.Fatalf("Stored state changed through returned copy: %#v", state)
+	}
+
+	if err := store.Delete("guild1"); err != nil {
+		t.Fatalf("Cannot delete game state: %v", err)
+	}
+	if state, _ := store.Get("guild1"); !cmp.Equal(&GameState{}, state) {
+		t.Fatalf("Expected empty state after delete, got %#v", state)
+	}
+	if state, _ := store.Get("guild2"); state.Phase != phaseDay || state.Day != 1 {
+		t.Fatalf("Expected guild2 to be unaffected by delete, got %#v", state)
+	}
+}
+
+func TestMemoryGameStore(t *testing.T) {
+	testGameStore(t, newMemoryGameStore())
+}
+
+func TestFileGameStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.json")
+	store, err := newFileGameStore(path)
+	if err != nil {
+		t.Fatalf("Cannot create file game store: %v", err)
+	}
+	testGameStore(t, store)
+
+	// Games survive restarts.
+	if err := store.Update("guild3", func(state *GameState) error {
+		state.enterPhase(phaseDay)
+		state.Players = []string{"user1"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	reopened, err := newFileGameStore(path)
+	if err != nil {
+		t.Fatalf("Cannot reopen file game store: %v", err)
+	}
+	for _, guild := range []string{"guild1", "guild2", "guild3"} {
+		want, _ := store.Get(guild)
+		got, err := reopened.Get(guild)
+		if err != nil {
+			t.Fatalf("Cannot get game state: %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Game state of %s changed after restart (-want, +got):%s\n", guild, diff)
+		}
+	}
+}
+
+func TestFileGameStoreWriteFailure(t *testing.T) {
+	store, err := newFileGameStore(filepath.Join(t.TempDir(), "games.json"))
+	if err != nil {
+		t.Fatalf("Cannot create file game store: %v", err)
+	}
+	if err := store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	// Nothing can be written to a directory that does not exist.
+	store.path = filepath.Join(t.TempDir(), "missing", "games.json")
+	if err := store.Update("guild", func(state *GameState) error {
+		state.Players = []string{"user1"}
+		return nil
+	}); err == nil {
+		t.Error("Expected error when the game state cannot be written, got nil")
+	}
+	if err := store.Delete("guild"); err == nil {
+		t.Error("Expected error when the game state cannot be written, got nil")
+	}
+
+	got, err := store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot get game state: %v", err)
+	}
+	if diff := cmp.Diff(&GameState{Running: true}, got); diff != "" {
+		t.Errorf("Unsaved game state was kept (-want, +got):%s\n", diff)
+	}
+}
+
+func TestEnterPhase(t *testing.T) {
+	state := &GameState{}
+	for _, tc := range []struct {
+		phase   string
+		wantDay int
+	}{
+		{phaseNight, 0},
+		{phaseDay, 1},
+		{phaseDay, 1},
+		{phaseNight, 1},
+		{phaseNight, 1},
+		{phaseDay, 2},
+	} {
+		state.enterPhase(tc.phase)
+		if state.Phase != tc.phase || state.Day != tc.wantDay {
+			t.Fatalf("Expected %s %d, got %s %d", tc.phase, tc.wantDay, state.Phase, state.Day)
+		}
+	}
+}
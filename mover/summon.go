@@ -0,0 +1,104 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Names of the user context menu commands to summon players to the story teller and back.
+const (
+	userCommandSummon = "Summon to Storyteller"
+	userCommandReturn = "Return from Storyteller"
+)
+
+// summon handles /summon and its context menu command. The player is moved into the voice
+// channel of the story teller, e.g. for a private chat.
+func (b *Bot) summon(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	user, err := commandTarget(i.ApplicationCommandData())
+	if err != nil {
+		return err
+	}
+
+	vs, err := b.buildDiscordVoiceState(ctx, s, i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot build voice state: %w", err)
+	}
+	storyTellerVoiceState := vs.userToVoiceState[i.Member.User.ID]
+	if storyTellerVoiceState == nil {
+		return fmt.Errorf("you are not in a voice channel")
+	}
+	channelID := storyTellerVoiceState.ChannelID
+
+	// Players muted in their cottage could not talk to the story teller.
+	var mutes map[string]bool
+	if userVoiceState := vs.userToVoiceState[user]; userVoiceState != nil && userVoiceState.Mute {
+		mutes = map[string]bool{user: false}
+	}
+
+	log.Printf("Summoning %s to %s in guild %s.", user, channelID, i.GuildID)
+	return b.movePlayer(ctx, s, i, vs, user, channelID, mutes, fmt.Sprintf("📣 Summoned <@%s> to <#%s>.", user, channelID))
+}
+
+// returnPlayer handles /return and its context menu command. At night, the player is moved back
+// into their cottage. During the day, they are moved back to Town Square.
+func (b *Bot) returnPlayer(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	user, err := commandTarget(i.ApplicationCommandData())
+	if err != nil {
+		return err
+	}
+
+	state, err := b.store.Get(i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot load game state: %w", err)
+	}
+	vs, err := b.buildDiscordVoiceState(ctx, s, i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot build voice state: %w", err)
+	}
+
+	channelID := vs.townSquare.ID
+	var mutes map[string]bool
+	if state.Phase == phaseNight {
+		cottageID, ok := state.Cottages[user]
+		if !ok || vs.channels[cottageID] == nil {
+			return fmt.Errorf("<@%s> has no cottage tonight", user)
+		}
+		channelID = cottageID
+
+		// Cottage occupants are muted or un-muted just like when the night started.
+		if cottageMute := b.cfg.ForGuild(i.GuildID).CottageMute; cottageMute != "" {
+			mute := cottageMute == cottageMuteOn
+			if userVoiceState := vs.userToVoiceState[user]; userVoiceState != nil && userVoiceState.Mute != mute {
+				mutes = map[string]bool{user: mute}
+			}
+		}
+	}
+
+	log.Printf("Returning %s to %s in guild %s.", user, channelID, i.GuildID)
+	return b.movePlayer(ctx, s, i, vs, user, channelID, mutes, fmt.Sprintf("↩️ Returned <@%s> to <#%s>.", user, channelID))
+}
+
+// movePlayer dispatches a plan that moves the player, who must be in a voice channel, into the
+// given channel and applies the mutes, if any.
+func (b *Bot) movePlayer(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, vs *discordVoiceState, user, channelID string, mutes map[string]bool, description string) error {
+	userVoiceState := vs.userToVoiceState[user]
+	if userVoiceState == nil {
+		return fmt.Errorf("<@%s> is not in a voice channel", user)
+	}
+
+	plan := &movementPlan{
+		moves:       map[string]string{user: channelID},
+		mutes:       mutes,
+		guild:       i.GuildID,
+		description: description,
+	}
+	if userVoiceState.ChannelID == channelID {
+		plan.moves = nil
+		plan.inPlace = map[string]string{user: channelID}
+	}
+	_, err := b.dispatchPlan(ctx, s, i, plan)
+	return err
+}
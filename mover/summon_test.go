@@ -0,0 +1,99 @@
+package mover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSummonAndReturn(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+		CottageMute:             cottageMuteOn,
+	})
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user1", ChannelID: "cottage1", Mute: true},
+			{UserID: "user2", ChannelID: "cottage2"},
+			{UserID: "storyteller", ChannelID: "cottage5"},
+		},
+	}
+	ctx := context.Background()
+	interaction := func(name, user string) *discordgo.InteractionCreate {
+		return &discordgo.InteractionCreate{
+			Interaction: &discordgo.Interaction{
+				Type:    discordgo.InteractionApplicationCommand,
+				GuildID: "guild",
+				Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}},
+				Data: discordgo.ApplicationCommandInteractionData{
+					Name:        name,
+					CommandType: discordgo.UserApplicationCommand,
+					TargetID:    user,
+				},
+			},
+		}
+	}
+	expectPlan := func(moves map[string]string, mutes map[string]bool, description string) {
+		t.Helper()
+		select {
+		case plan := <-plans:
+			if diff := cmp.Diff(moves, plan.moves); diff != "" {
+				t.Errorf("Moves mismatch (-want, +got):%s\n", diff)
+			}
+			if diff := cmp.Diff(mutes, plan.mutes); diff != "" {
+				t.Errorf("Mutes mismatch (-want, +got):%s\n", diff)
+			}
+			if plan.description != description {
+				t.Errorf("Unexpected description %q, want %q", plan.description, description)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected to receive plan, got nothing.")
+		}
+		b.plans.Wait()
+	}
+
+	if err := b.summon(ctx, d, interaction(userCommandSummon, "user1")); err != nil {
+		t.Fatalf("Cannot summon player: %v", err)
+	}
+	// The player is un-muted to talk to the story teller.
+	expectPlan(map[string]string{"user1": "cottage5"}, map[string]bool{"user1": false}, "📣 Summoned <@user1> to <#cottage5>.")
+
+	if err := b.summon(ctx, d, interaction(userCommandSummon, "user3")); err == nil {
+		t.Error("Expected error when summoning a player who is not in a voice channel, got nil")
+	}
+
+	// Before the night, players return to Town Square.
+	d.voiceStates[0] = &discordgo.VoiceState{UserID: "user1", ChannelID: "cottage5"}
+	if err := b.returnPlayer(ctx, d, interaction(userCommandReturn, "user1")); err != nil {
+		t.Fatalf("Cannot return player: %v", err)
+	}
+	expectPlan(map[string]string{"user1": "townsquare"}, nil, "↩️ Returned <@user1> to <#townsquare>.")
+
+	// At night, players return to their own cottage and are muted again.
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.enterPhase(phaseNight)
+		state.Cottages = map[string]string{"user1": "cottage3"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+	if err := b.returnPlayer(ctx, d, interaction(userCommandReturn, "user1")); err != nil {
+		t.Fatalf("Cannot return player: %v", err)
+	}
+	expectPlan(map[string]string{"user1": "cottage3"}, map[string]bool{"user1": true}, "↩️ Returned <@user1> to <#cottage3>.")
+
+	if err := b.returnPlayer(ctx, d, interaction(userCommandReturn, "user2")); err == nil {
+		t.Error("Expected error when returning a player without a cottage at night, got nil")
+	}
+}
@@ -0,0 +1,330 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// maxTimerDuration is the longest countdown /timer accepts.
+const maxTimerDuration = time.Hour
+
+// timerUpdateInterval is the interval in which the countdown message is updated. Discord renders
+// the relative deadline in the message live, the updates only refresh the remaining time.
+const timerUpdateInterval = 10 * time.Second
+
+// timerTickInterval is the interval in which running timers check for warnings and expiry.
+const timerTickInterval = time.Second
+
+// defaultTimerWarnings are the remaining times at which timers warn if no warnings are configured.
+var defaultTimerWarnings = []int{60, 10}
+
+// countdown is a timer started with /timer. It posts a message in the channel it was started in
+// and keeps it up to date until the time is up.
+type countdown struct {
+	guild   string
+	channel string
+	message string
+	label   string
+	// deadline is the time at which the countdown ends.
+	deadline time.Time
+	// warnings contains all remaining times at which a warning is still pending, longest first.
+	warnings []time.Duration
+	// night is true iff the night starts once the time is up.
+	night bool
+	// interaction is the /timer interaction, which also starts the night.
+	interaction *discordgo.InteractionCreate
+	lastUpdate  time.Time
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// cancel stops the countdown before its time is up.
+func (t *countdown) cancel() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+// timerRegistry keeps track of the running timer of every guild.
+type timerRegistry struct {
+	mu sync.Mutex
+	// timers maps guild IDs to running timers.
+	timers map[string]*countdown
+}
+
+func newTimerRegistry() *timerRegistry {
+	return &timerRegistry{timers: make(map[string]*countdown)}
+}
+
+// swap makes t the running timer of its guild and returns the previous one, if any. A nil t only
+// removes the running timer.
+func (r *timerRegistry) swap(guild string, t *countdown) *countdown {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.timers[guild]
+	if t == nil {
+		delete(r.timers, guild)
+	} else {
+		r.timers[guild] = t
+	}
+	return previous
+}
+
+// remove removes the timer iff it is still the running timer of its guild.
+func (r *timerRegistry) remove(t *countdown) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.timers[t.guild] == t {
+		delete(r.timers, t.guild)
+	}
+}
+
+// parseTimerDuration parses the duration of a timer. Plain numbers are minutes, everything else
+// has to be a duration such as 90s or 2m30s.
+func parseTimerDuration(value string) (time.Duration, error) {
+	if minutes, err := strconv.Atoi(value); err == nil {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, use e.g. 5 (minutes), 90s or 2m30s", value)
+	}
+	return d, nil
+}
+
+// formatRemaining formats the remaining time of a timer in whole seconds.
+func formatRemaining(d time.Duration) string {
+	seconds := int((d + time.Second - 1) / time.Second)
+	switch {
+	case seconds < 60:
+		return fmt.Sprintf("%ds", seconds)
+	case seconds%60 == 0:
+		return fmt.Sprintf("%dm", seconds/60)
+	}
+	return fmt.Sprintf("%dm %ds", seconds/60, seconds%60)
+}
+
+// startTimer handles the /timer slash command. It posts the countdown in the channel and replaces
+// the running timer of the guild, if any. A duration of 0 only stops the running timer.
+func (b *Bot) startTimer(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	var duration time.Duration
+	var label string
+	var night bool
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "duration":
+			d, err := parseTimerDuration(option.StringValue())
+			if err != nil {
+				return err
+			}
+			duration = d
+		case "label":
+			label = option.StringValue()
+		case "night":
+			night = option.BoolValue()
+		}
+	}
+
+	if duration == 0 {
+		previous := b.timers.swap(i.GuildID, nil)
+		if previous == nil {
+			return respondEphemeral(ctx, s, i, "There is no running timer.")
+		}
+		previous.cancel()
+		return respondEphemeral(ctx, s, i, "Stopped the running timer.")
+	}
+	if duration < 0 || duration > maxTimerDuration {
+		return fmt.Errorf("invalid duration %v, must be between 1s and %v", duration, maxTimerDuration)
+	}
+
+	cfg := b.cfg.ForGuild(i.GuildID)
+	warnings := cfg.TimerWarnings
+	if warnings == nil {
+		warnings = defaultTimerWarnings
+	}
+
+	now := time.Now()
+	t := &countdown{
+		guild:       i.GuildID,
+		channel:     i.ChannelID,
+		label:       label,
+		deadline:    now.Add(duration),
+		night:       night,
+		interaction: i,
+		lastUpdate:  now,
+		stop:        make(chan struct{}),
+	}
+	// Warnings longer than the timer itself are pointless.
+	for _, seconds := range warnings {
+		if warning := time.Duration(seconds) * time.Second; warning < duration {
+			t.warnings = append(t.warnings, warning)
+		}
+	}
+	slices.SortFunc(t.warnings, func(a, b time.Duration) int {
+		return int(b - a)
+	})
+
+	message, err := s.ChannelMessageSendComplex(t.channel, &discordgo.MessageSend{
+		Content:         renderCountdown(t, now),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("cannot post timer: %w", err)
+	}
+	t.message = message.ID
+
+	if previous := b.timers.swap(i.GuildID, t); previous != nil {
+		previous.cancel()
+	}
+	go b.runTimer(s, t)
+	log.Printf("Started %v timer in channel %s of guild %s.", duration, t.channel, t.guild)
+
+	content := fmt.Sprintf("Started a %s timer.", formatRemaining(duration))
+	if night {
+		content = fmt.Sprintf("Started a %s timer, the night starts when the time is up.", formatRemaining(duration))
+	}
+	return respondEphemeral(ctx, s, i, content)
+}
+
+// title returns the label of the timer, or a generic title if it has none.
+func (t *countdown) title() string {
+	if t.label == "" {
+		return "Timer"
+	}
+	return t.label
+}
+
+// renderCountdown renders the countdown message of the timer.
+func renderCountdown(t *countdown, now time.Time) string {
+	title := t.title()
+	remaining := t.deadline.Sub(now)
+	if remaining <= 0 {
+		return fmt.Sprintf("⌛ **%s**: Time is up!", title)
+	}
+	content := fmt.Sprintf("⏳ **%s** ends <t:%d:R> (%s left).", title, t.deadline.Unix(), formatRemaining(remaining))
+	if t.night {
+		content += " The night starts when the time is up."
+	}
+	return content
+}
+
+// runTimer runs the countdown until its time is up or it is cancelled.
+func (b *Bot) runTimer(s discordSession, t *countdown) {
+	ticker := time.NewTicker(timerTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			b.updateTimer(s, t, fmt.Sprintf("⏹️ **%s**: Timer stopped.", t.title()))
+			return
+		case now := <-ticker.C:
+			if !b.tickTimer(s, t, now) {
+				continue
+			}
+			b.timers.remove(t)
+			if t.night {
+				b.startTimerNight(s, t)
+			}
+			return
+		}
+	}
+}
+
+// tickTimer posts all pending warnings and updates the countdown message. Returns true iff the
+// time is up.
+func (b *Bot) tickTimer(s discordSession, t *countdown, now time.Time) bool {
+	remaining := t.deadline.Sub(now)
+	if remaining <= 0 {
+		b.updateTimer(s, t, renderCountdown(t, now))
+		b.postTimerMessage(s, t, renderCountdown(t, now))
+		return true
+	}
+
+	var warned bool
+	for len(t.warnings) > 0 && remaining <= t.warnings[0] {
+		// Only the shortest of several passed warnings is posted, e.g. after a hiccup.
+		if len(t.warnings) == 1 || remaining > t.warnings[1] {
+			b.postTimerMessage(s, t, fmt.Sprintf("⏰ **%s**: %s left!", t.title(), formatRemaining(t.warnings[0])))
+			warned = true
+		}
+		t.warnings = t.warnings[1:]
+	}
+
+	if warned || now.Sub(t.lastUpdate) >= timerUpdateInterval {
+		b.updateTimer(s, t, renderCountdown(t, now))
+		t.lastUpdate = now
+	}
+	return false
+}
+
+// updateTimer replaces the content of the countdown message.
+func (b *Bot) updateTimer(s discordSession, t *countdown, content string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(b.cfg.ForGuild(t.guild).PerRequestSeconds)*time.Second)
+	defer cancel()
+
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:              t.message,
+		Channel:         t.channel,
+		Content:         &content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}, discordgo.WithContext(ctx)); err != nil {
+		log.Printf("Cannot update timer in channel %s of guild %s: %v", t.channel, t.guild, err)
+	}
+}
+
+// postTimerMessage posts a new message in the channel of the timer, which notifies everyone unlike
+// edits of the countdown message.
+func (b *Bot) postTimerMessage(s discordSession, t *countdown, content string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(b.cfg.ForGuild(t.guild).PerRequestSeconds)*time.Second)
+	defer cancel()
+
+	if _, err := s.ChannelMessageSendComplex(t.channel, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}, discordgo.WithContext(ctx)); err != nil {
+		log.Printf("Cannot post timer message in channel %s of guild %s: %v", t.channel, t.guild, err)
+	}
+}
+
+// acknowledgedSession is a discord session for interactions that have been acknowledged already.
+// Used to run button actions long after the interaction that triggered them, whose token may have
+// expired by then. Responses are posted as new messages in the channel instead.
+type acknowledgedSession struct {
+	discordSession
+	channel string
+}
+
+func (acknowledgedSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	return nil
+}
+
+func (a acknowledgedSession) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	if newresp.Content == nil {
+		return nil, nil
+	}
+	return a.ChannelMessageSendComplex(a.channel, &discordgo.MessageSend{
+		Content:         *newresp.Content,
+		AllowedMentions: newresp.AllowedMentions,
+	}, options...)
+}
+
+// startTimerNight starts the night once the time of the timer is up, just like the night button
+// would. The movement report is posted in the channel of the timer.
+func (b *Bot) startTimerNight(s discordSession, t *countdown) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(b.cfg.ForGuild(t.guild).PerRequestSeconds)*time.Second)
+	defer cancel()
+
+	log.Printf("Timer in guild %s is up, moving to night.", t.guild)
+	if err := b.prepareNightMoves(ctx, acknowledgedSession{s, t.channel}, t.interaction); err != nil {
+		log.Printf("Cannot start night in guild %s: %v", t.guild, err)
+		b.postTimerMessage(s, t, fmt.Sprintf("Cannot start the night: %v", err))
+	}
+}
@@ -0,0 +1,232 @@
+package mover
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func timerInteraction(duration, label string, night bool) *discordgo.InteractionCreate {
+	data := discordgo.ApplicationCommandInteractionData{
+		Name: slashCommandTimer,
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "duration", Type: discordgo.ApplicationCommandOptionString, Value: duration},
+			{Name: "night", Type: discordgo.ApplicationCommandOptionBoolean, Value: night},
+		},
+	}
+	if label != "" {
+		data.Options = append(data.Options, &discordgo.ApplicationCommandInteractionDataOption{
+			Name: "label", Type: discordgo.ApplicationCommandOptionString, Value: label,
+		})
+	}
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:      discordgo.InteractionApplicationCommand,
+			GuildID:   "guild",
+			ChannelID: "text",
+			Member:    &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}},
+			Data:      data,
+		},
+	}
+}
+
+func TestParseTimerDuration(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"5", 5 * time.Minute, false},
+		{"0", 0, false},
+		{"90s", 90 * time.Second, false},
+		{"2m30s", 150 * time.Second, false},
+		{"soon", 0, true},
+	} {
+		got, err := parseTimerDuration(tc.value)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseTimerDuration(%q) returned unexpected error %v, want error: %t", tc.value, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("parseTimerDuration(%q) = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestFormatRemaining(t *testing.T) {
+	for _, tc := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{10 * time.Second, "10s"},
+		{9500 * time.Millisecond, "10s"},
+		{time.Minute, "1m"},
+		{150 * time.Second, "2m 30s"},
+	} {
+		if got := formatRemaining(tc.d); got != tc.want {
+			t.Errorf("formatRemaining(%v) = %q, want %q", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestTickTimer(t *testing.T) {
+	b, _ := newTestBot(&Config{PerRequestSeconds: 5})
+	d := &fakeDiscordSession{id: "guild"}
+
+	start := time.Now()
+	timer := &countdown{
+		guild:      "guild",
+		channel:    "text",
+		message:    "message",
+		label:      "Nominations",
+		deadline:   start.Add(2 * time.Minute),
+		warnings:   []time.Duration{time.Minute, 30 * time.Second, 10 * time.Second},
+		lastUpdate: start,
+	}
+
+	var got []string
+	for _, tc := range []struct {
+		elapsed  time.Duration
+		wantDone bool
+	}{
+		// Nothing to do yet.
+		{time.Second, false},
+		// Updates the countdown.
+		{15 * time.Second, false},
+		// First warning.
+		{61 * time.Second, false},
+		// Both remaining warnings passed at once, only the last one is posted.
+		{111 * time.Second, false},
+		{2 * time.Minute, true},
+	} {
+		if done := b.tickTimer(d, timer, start.Add(tc.elapsed)); done != tc.wantDone {
+			t.Errorf("tickTimer() after %v returned %t, want %t", tc.elapsed, done, tc.wantDone)
+		}
+	}
+	for _, message := range d.messages {
+		got = append(got, message.Content)
+	}
+
+	want := []string{
+		"⏰ **Nominations**: 1m left!",
+		"⏰ **Nominations**: 10s left!",
+		"⌛ **Nominations**: Time is up!",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Posted messages mismatch (-want, +got):%s\n", diff)
+	}
+	if len(d.messageEdits) != 4 {
+		t.Errorf("Expected 4 countdown updates, got %d", len(d.messageEdits))
+	}
+	if got := *d.messageEdits[0].Content; !strings.Contains(got, "(1m 45s left)") {
+		t.Errorf("Unexpected countdown update %q", got)
+	}
+}
+
+func TestStartTimer(t *testing.T) {
+	b, _ := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+		TimerWarnings:           []int{600, 60},
+	})
+	d := &fakeDiscordSession{id: "guild"}
+	ctx := context.Background()
+
+	if err := b.startTimer(ctx, d, timerInteraction("5", "Discussion", false)); err != nil {
+		t.Fatalf("Cannot start timer: %v", err)
+	}
+	first := b.timers.swap("guild", nil)
+	if first == nil {
+		t.Fatal("Expected a running timer, got none.")
+	}
+	b.timers.swap("guild", first)
+	// Warnings longer than the timer are dropped.
+	if diff := cmp.Diff([]time.Duration{time.Minute}, first.warnings); diff != "" {
+		t.Errorf("Timer warnings mismatch (-want, +got):%s\n", diff)
+	}
+	if got := d.messages[0].Content; !strings.HasPrefix(got, "⏳ **Discussion** ends <t:") {
+		t.Errorf("Unexpected countdown %q", got)
+	}
+
+	// A new timer replaces the running one.
+	if err := b.startTimer(ctx, d, timerInteraction("90s", "", true)); err != nil {
+		t.Fatalf("Cannot start timer: %v", err)
+	}
+	select {
+	case <-first.stop:
+	default:
+		t.Error("Expected the first timer to be stopped.")
+	}
+	if got := d.responses[1].Data.Content; got != "Started a 1m 30s timer, the night starts when the time is up." {
+		t.Errorf("Unexpected response %q", got)
+	}
+
+	if err := b.startTimer(ctx, d, timerInteraction("0", "", false)); err != nil {
+		t.Fatalf("Cannot stop timer: %v", err)
+	}
+	if b.timers.swap("guild", nil) != nil {
+		t.Error("Expected no running timer after stopping it.")
+	}
+
+	for _, duration := range []string{"2h", "-1m", "later"} {
+		if err := b.startTimer(ctx, d, timerInteraction(duration, "", false)); err == nil {
+			t.Errorf("Expected error for duration %q, got nil", duration)
+		}
+	}
+}
+
+func TestTimerStartsNight(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	d := &fakeDiscordSession{id: "guild"}
+
+	i := timerInteraction("1", "", true)
+	b.startTimerNight(d, &countdown{guild: "guild", channel: "text", night: true, interaction: i})
+
+	select {
+	case plan := <-plans:
+		if len(plan.moves) != 5 {
+			t.Errorf("Expected 5 moves, got %v", plan.moves)
+		}
+		// The /timer interaction may have expired, the movement report is posted in the channel.
+		content := "Moved: 5"
+		if _, err := plan.session.InteractionResponseEdit(plan.interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			t.Fatalf("Cannot report movement result: %v", err)
+		}
+		if len(d.messages) != 1 || d.messages[0].Content != content {
+			t.Errorf("Expected the movement report to be posted in the channel, got %v", d.messages)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	// The interaction has been acknowledged when the timer was started.
+	if len(d.responses) != 0 {
+		t.Errorf("Expected no further interaction responses, got %d", len(d.responses))
+	}
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if state.Phase != phaseNight {
+		t.Errorf("Expected night phase, got %q", state.Phase)
+	}
+}
@@ -0,0 +1,120 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// Subcommands of the /traveller slash command.
+const (
+	travellerCommandAdd    = "add"
+	travellerCommandRemove = "remove"
+)
+
+// isTraveller returns true iff the user is a seated traveller.
+func (g *GameState) isTraveller(user string) bool {
+	return slices.Contains(g.Travellers, user)
+}
+
+// onTravellerCommand handles the /traveller slash command group.
+func (b *Bot) onTravellerCommand(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return fmt.Errorf("missing /traveller subcommand")
+	}
+
+	var user string
+	var seat int
+	for _, option := range options[0].Options {
+		switch option.Name {
+		case "player":
+			user = option.UserValue(nil).ID
+		case "seat":
+			seat = int(option.IntValue())
+		}
+	}
+	if user == "" {
+		return fmt.Errorf("missing player")
+	}
+
+	switch options[0].Name {
+	case travellerCommandAdd:
+		return b.addTraveller(ctx, s, i, user, seat)
+	case travellerCommandRemove:
+		return b.removeTraveller(ctx, s, i, user)
+	}
+
+	return fmt.Errorf("unknown /traveller subcommand: %s", options[0].Name)
+}
+
+// addTraveller seats the user as a traveller. Travellers take the given seat, which moves everyone
+// from that seat on one seat further, or the last seat if seat is 0.
+func (b *Bot) addTraveller(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, user string, seat int) error {
+	if err := b.store.Update(i.GuildID, func(state *GameState) error {
+		switch {
+		case !state.Running:
+			return fmt.Errorf("no game is running")
+		case slices.Contains(state.Players, user):
+			return fmt.Errorf("<@%s> is seated in the game already", user)
+		case seat < 0 || seat > len(state.Players)+1:
+			return fmt.Errorf("invalid seat %d, must be between 1 and %d", seat, len(state.Players)+1)
+		}
+		if seat == 0 {
+			seat = len(state.Players) + 1
+		}
+		state.Players = slices.Insert(state.Players, seat-1, user)
+		state.Travellers = append(state.Travellers, user)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	log.Printf("Added traveller %s in seat %d in guild %s.", user, seat, i.GuildID)
+	return respondEphemeral(ctx, s, i, fmt.Sprintf("<@%s> joined the game as a traveller in seat %d.", user, seat))
+}
+
+// removeTraveller removes the traveller from the game. They are no longer moved at night and no
+// longer count towards the vote threshold.
+func (b *Bot) removeTraveller(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, user string) error {
+	var original string
+	var hasOriginal bool
+	if err := b.store.Update(i.GuildID, func(state *GameState) error {
+		switch {
+		case !state.Running:
+			return fmt.Errorf("no game is running")
+		case !state.isTraveller(user):
+			return fmt.Errorf("<@%s> is not a traveller", user)
+		}
+		if n := state.openNomination(); n != nil {
+			if n.Nominator == user || n.Nominee == user {
+				return fmt.Errorf("the votes on <@%s> have not been counted yet", n.Nominee)
+			}
+			n.Hands = slices.DeleteFunc(n.Hands, func(hand string) bool { return hand == user })
+		}
+		isUser := func(other string) bool { return other == user }
+		state.Players = slices.DeleteFunc(state.Players, isUser)
+		state.Travellers = slices.DeleteFunc(state.Travellers, isUser)
+		state.Dead = slices.DeleteFunc(state.Dead, isUser)
+		state.GhostVotesUsed = slices.DeleteFunc(state.GhostVotesUsed, isUser)
+		delete(state.Cottages, user)
+		delete(state.Grimoire, user)
+		original, hasOriginal = state.Nicknames[user]
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	log.Printf("Removed traveller %s in guild %s.", user, i.GuildID)
+	content := fmt.Sprintf("<@%s> left the game.", user)
+	if hasOriginal {
+		if err := b.restoreNicknames(ctx, s, i.GuildID, map[string]string{user: original}); err != nil {
+			log.Printf("Cannot restore nickname of %s in guild %s: %v", user, i.GuildID, err)
+			content += fmt.Sprintf(" Cannot restore their nickname: %v", err)
+		}
+	}
+	return respondEphemeral(ctx, s, i, content)
+}
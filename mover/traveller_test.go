@@ -0,0 +1,194 @@
+package mover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func travellerInteraction(subcommand, user string, seat int) *discordgo.InteractionCreate {
+	option := &discordgo.ApplicationCommandInteractionDataOption{
+		Name: subcommand,
+		Type: discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "player", Type: discordgo.ApplicationCommandOptionUser, Value: user},
+		},
+	}
+	if seat != 0 {
+		option.Options = append(option.Options, &discordgo.ApplicationCommandInteractionDataOption{
+			Name: "seat", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(seat),
+		})
+	}
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name:    slashCommandTraveller,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{option},
+			},
+		},
+	}
+}
+
+func TestTravellerCommand(t *testing.T) {
+	b, _ := newTestBot(&Config{PerRequestSeconds: 5})
+	d := &fakeDiscordSession{id: "guild"}
+	ctx := context.Background()
+
+	if err := b.onTravellerCommand(ctx, d, travellerInteraction(travellerCommandAdd, "user4", 0)); err == nil {
+		t.Error("Expected error without a running game, got nil")
+	}
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"user1", "user2", "user3"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	if err := b.onTravellerCommand(ctx, d, travellerInteraction(travellerCommandAdd, "user4", 2)); err != nil {
+		t.Fatalf("Cannot add traveller: %v", err)
+	}
+	if got := d.responses[len(d.responses)-1].Data.Content; got != "<@user4> joined the game as a traveller in seat 2." {
+		t.Errorf("Unexpected response %q", got)
+	}
+	if err := b.onTravellerCommand(ctx, d, travellerInteraction(travellerCommandAdd, "user5", 0)); err != nil {
+		t.Fatalf("Cannot add traveller: %v", err)
+	}
+	for _, tc := range []struct {
+		desc       string
+		subcommand string
+		user       string
+		seat       int
+	}{
+		{"already seated", travellerCommandAdd, "user1", 0},
+		{"seat out of range", travellerCommandAdd, "user6", 7},
+		{"not a traveller", travellerCommandRemove, "user1", 0},
+	} {
+		if err := b.onTravellerCommand(ctx, d, travellerInteraction(tc.subcommand, tc.user, tc.seat)); err == nil {
+			t.Errorf("%s: Expected error, got nil", tc.desc)
+		}
+	}
+
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if diff := cmp.Diff([]string{"user1", "user4", "user2", "user3", "user5"}, state.Players); diff != "" {
+		t.Errorf("Seated players mismatch (-want, +got):%s\n", diff)
+	}
+	if got := state.voteThreshold(); got != 3 {
+		t.Errorf("Expected a vote threshold of 3 with travellers, got %d", got)
+	}
+
+	// Removed travellers no longer count.
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Dead = []string{"user4"}
+		state.Cottages = map[string]string{"user4": "cottage2"}
+		state.Nicknames = map[string]string{"user1": "One", "user4": "Four"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+	if err := b.onTravellerCommand(ctx, d, travellerInteraction(travellerCommandRemove, "user4", 0)); err != nil {
+		t.Fatalf("Cannot remove traveller: %v", err)
+	}
+	state, err = b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	want := &GameState{
+		Running:    true,
+		Players:    []string{"user1", "user2", "user3", "user5"},
+		Travellers: []string{"user5"},
+		Dead:       []string{},
+		Cottages:   map[string]string{},
+		// The nickname of the traveller has been restored already.
+		Nicknames: map[string]string{"user1": "One"},
+	}
+	if diff := cmp.Diff(want, state); diff != "" {
+		t.Errorf("Game state mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestRemoveTravellerDuringNomination(t *testing.T) {
+	b, _ := newTestBot(&Config{PerRequestSeconds: 5})
+	d := &fakeDiscordSession{id: "guild"}
+	ctx := context.Background()
+
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"user1", "user2", "user3", "user4"}
+		state.Travellers = []string{"user3", "user4"}
+		state.Nominations = []*Nomination{{Day: 1, Nominator: "user1", Nominee: "user4", Hands: []string{"user2", "user3"}}}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	if err := b.onTravellerCommand(ctx, d, travellerInteraction(travellerCommandRemove, "user4", 0)); err == nil {
+		t.Error("Expected error when removing the nominee of an open nomination, got nil")
+	}
+	// Other travellers lower their hand when they leave.
+	if err := b.onTravellerCommand(ctx, d, travellerInteraction(travellerCommandRemove, "user3", 0)); err != nil {
+		t.Fatalf("Cannot remove traveller: %v", err)
+	}
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if diff := cmp.Diff([]string{"user2"}, state.Nominations[0].Hands); diff != "" {
+		t.Errorf("Raised hands mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestPrepareNightMovesSkipsRemovedTravellers(t *testing.T) {
+	b, plans := newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+	d := &fakeDiscordSession{id: "guild"}
+	ctx := context.Background()
+
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Players = []string{"user1", "user2", "user3"}
+		state.Travellers = []string{"user3"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+	if err := b.onTravellerCommand(ctx, d, travellerInteraction(travellerCommandRemove, "user3", 0)); err != nil {
+		t.Fatalf("Cannot remove traveller: %v", err)
+	}
+
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}},
+		},
+	}
+	if err := b.prepareNightMoves(ctx, d, i); err != nil {
+		t.Fatalf("Cannot prepare night moves: %v", err)
+	}
+	select {
+	case plan := <-plans:
+		if _, ok := plan.moves["user3"]; ok {
+			t.Errorf("Expected the removed traveller to stay, got moves %v", plan.moves)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+}
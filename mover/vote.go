@@ -0,0 +1,346 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// Buttons of the vote message. Their custom IDs contain the game and the number of the
+// nomination, e.g. "buttonVote:1700000000000000000:3", so that buttons of old vote messages
+// cannot affect the current vote.
+const (
+	buttonVote             = "buttonVote"
+	buttonCountVotes       = "buttonCountVotes"
+	buttonCancelNomination = "buttonCancelNomination"
+)
+
+// nominationButtonID returns the custom ID of the button for the n-th nomination of the game.
+func nominationButtonID(button string, game int64, n int) string {
+	return fmt.Sprintf("%s:%d:%d", button, game, n)
+}
+
+// parseNominationButton parses the custom ID of a vote message button into the button, the game
+// and the number of the nomination.
+func parseNominationButton(customID string) (string, int64, int, bool) {
+	parts := strings.Split(customID, ":")
+	if len(parts) != 3 {
+		return "", 0, 0, false
+	}
+	game, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	n, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return "", 0, 0, false
+	}
+	return parts[0], game, n, true
+}
+
+// isAlive returns true iff the user is not dead.
+func (g *GameState) isAlive(user string) bool {
+	return !slices.Contains(g.Dead, user)
+}
+
+// living returns the number of seated players who are alive.
+func (g *GameState) living() int {
+	var n int
+	for _, player := range g.Players {
+		if g.isAlive(player) {
+			n++
+		}
+	}
+	return n
+}
+
+// voudon is the ID of the Voudon, a traveller who changes how votes work while they live.
+const voudon = "voudon"
+
+// voudonAlive returns true iff a living player is the Voudon according to the grimoire. While the
+// Voudon lives, only they and the dead vote, the dead do not need their ghost vote to do so and no
+// majority is needed.
+func (g *GameState) voudonAlive() bool {
+	for _, player := range g.Players {
+		if entry := g.Grimoire[player]; entry != nil && entry.Character == voudon && g.isAlive(player) {
+			return true
+		}
+	}
+	return false
+}
+
+// voteThreshold returns the number of votes required to execute a player, i.e. half of the living
+// players rounded up, or a single vote while the Voudon lives.
+func (g *GameState) voteThreshold() int {
+	if g.voudonAlive() {
+		return 1
+	}
+	return (g.living() + 1) / 2
+}
+
+// hasVote returns true iff the player may vote. Dead players only have a single ghost vote.
+func (g *GameState) hasVote(user string) bool {
+	if g.voudonAlive() {
+		entry := g.Grimoire[user]
+		return !g.isAlive(user) || entry != nil && entry.Character == voudon
+	}
+	return g.isAlive(user) || !slices.Contains(g.GhostVotesUsed, user)
+}
+
+// openNomination returns the nomination whose votes have not been counted yet, if any.
+func (g *GameState) openNomination() *Nomination {
+	if len(g.Nominations) == 0 || g.Nominations[len(g.Nominations)-1].Counted {
+		return nil
+	}
+	return g.Nominations[len(g.Nominations)-1]
+}
+
+// clockwiseFrom returns all seated players in clockwise order, starting with the player after the
+// given one and ending with the player themselves.
+func (g *GameState) clockwiseFrom(user string) []string {
+	start := slices.Index(g.Players, user)
+	var players []string
+	for n := range g.Players {
+		players = append(players, g.Players[(start+1+n)%len(g.Players)])
+	}
+	return players
+}
+
+// countVotes counts the raised hands of the nomination clockwise, starting after the nominee.
+// Dead players use up their ghost vote, unless the Voudon lives.
+func (g *GameState) countVotes(n *Nomination) {
+	n.Voters = nil
+	ghostVotes := !g.voudonAlive()
+	for _, player := range g.clockwiseFrom(n.Nominee) {
+		if !slices.Contains(n.Hands, player) || !g.hasVote(player) {
+			continue
+		}
+		n.Voters = append(n.Voters, player)
+		if ghostVotes && !g.isAlive(player) {
+			g.GhostVotesUsed = append(g.GhostVotesUsed, player)
+		}
+	}
+	n.Threshold = g.voteThreshold()
+	n.Counted = true
+}
+
+// onTheBlock returns the player who is about to be executed on the given day and their number of
+// votes. Nobody is about to be executed if the highest vote is tied or no vote reached its
+// threshold.
+func (g *GameState) onTheBlock(day int) (string, int) {
+	var block string
+	var highest int
+	for _, n := range g.Nominations {
+		if n.Day != day || !n.Counted || len(n.Voters) < n.Threshold {
+			continue
+		}
+		switch votes := len(n.Voters); {
+		case votes > highest:
+			block, highest = n.Nominee, votes
+		case votes == highest:
+			block = "" // Tied, nobody is executed.
+		}
+	}
+	return block, highest
+}
+
+// nominate handles the /nominate slash command and posts the vote message. Every player may
+// nominate and be nominated once per day, only living players may nominate.
+func (b *Bot) nominate(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	var nominator, nominee string
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "nominator":
+			nominator = option.UserValue(nil).ID
+		case "nominee":
+			nominee = option.UserValue(nil).ID
+		}
+	}
+
+	var state *GameState
+	if err := b.store.Update(i.GuildID, func(g *GameState) error {
+		switch {
+		case !g.Running:
+			return fmt.Errorf("no game is running")
+		case g.Phase != phaseDay:
+			return fmt.Errorf("nominations are only possible during the day")
+		case g.openNomination() != nil:
+			return fmt.Errorf("the votes on <@%s> have not been counted yet", g.openNomination().Nominee)
+		case !slices.Contains(g.Players, nominator):
+			return fmt.Errorf("<@%s> is not seated in the game", nominator)
+		case !slices.Contains(g.Players, nominee):
+			return fmt.Errorf("<@%s> is not seated in the game", nominee)
+		case !g.isAlive(nominator):
+			return fmt.Errorf("<@%s> is dead and cannot nominate", nominator)
+		}
+		for _, n := range g.Nominations {
+			if n.Day != g.Day {
+				continue
+			}
+			if n.Nominator == nominator {
+				return fmt.Errorf("<@%s> has already nominated today", nominator)
+			}
+			if n.Nominee == nominee {
+				return fmt.Errorf("<@%s> has already been nominated today", nominee)
+			}
+		}
+		g.Nominations = append(g.Nominations, &Nomination{Day: g.Day, Nominator: nominator, Nominee: nominee})
+		state = g.clone()
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	log.Printf("%s nominated %s in guild %s.", nominator, nominee, i.GuildID)
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: renderVoteMessage(state, len(state.Nominations)),
+	}, discordgo.WithContext(ctx))
+}
+
+// onNominationButton handles the buttons of the n-th nomination's vote message of the given game.
+// Players raise or lower their hand, story tellers count the votes or cancel the nomination.
+func (b *Bot) onNominationButton(ctx context.Context, s discordSession, i *discordgo.InteractionCreate, button string, game int64, number int) error {
+	var state *GameState
+	if err := b.store.Update(i.GuildID, func(g *GameState) error {
+		if game != g.Game || number < 1 || number > len(g.Nominations) {
+			return fmt.Errorf("this nomination belongs to a previous game")
+		}
+		n := g.Nominations[number-1]
+		if n.Counted {
+			return fmt.Errorf("the votes have been counted already")
+		}
+
+		switch button {
+		case buttonVote:
+			voter := i.Member.User.ID
+			if !slices.Contains(g.Players, voter) {
+				return fmt.Errorf("only seated players can vote")
+			}
+			if !g.hasVote(voter) {
+				if g.voudonAlive() {
+					return fmt.Errorf("only the Voudon and the dead can vote")
+				}
+				return fmt.Errorf("you have used your ghost vote already")
+			}
+			if index := slices.Index(n.Hands, voter); index >= 0 {
+				n.Hands = slices.Delete(n.Hands, index, index+1)
+			} else {
+				n.Hands = append(n.Hands, voter)
+			}
+		case buttonCountVotes:
+			g.countVotes(n)
+			log.Printf("Counted %d vote(s) on %s in guild %s.", len(n.Voters), n.Nominee, i.GuildID)
+		case buttonCancelNomination:
+			g.Nominations = g.Nominations[:number-1]
+		default:
+			return fmt.Errorf("unknown nomination button %s", button)
+		}
+		state = g.clone()
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if button == buttonCancelNomination {
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "The nomination has been cancelled.",
+				Components: []discordgo.MessageComponent{},
+			},
+		}, discordgo.WithContext(ctx))
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: renderVoteMessage(state, number),
+	}, discordgo.WithContext(ctx))
+}
+
+// renderVoteMessage renders the vote message of the n-th nomination. Open votes have buttons to
+// vote and to count the votes.
+func renderVoteMessage(state *GameState, number int) *discordgo.InteractionResponseData {
+	n := state.Nominations[number-1]
+	lines := []string{fmt.Sprintf("**Nomination %d of day %d:** <@%s> nominates <@%s>.", number, n.Day, n.Nominator, n.Nominee)}
+
+	if !n.Counted {
+		var hands []string
+		for _, player := range state.clockwiseFrom(n.Nominee) {
+			if slices.Contains(n.Hands, player) {
+				hands = append(hands, player)
+			}
+		}
+		rule := "Dead players have a single ghost vote."
+		if state.voudonAlive() {
+			rule = "Only the Voudon and the dead vote."
+		}
+		lines = append(lines, fmt.Sprintf("%d vote(s) needed, %d player(s) alive. %s", state.voteThreshold(), state.living(), rule))
+		lines = append(lines, fmt.Sprintf("✋ Hands raised (%d): %s", len(hands), renderVoters(state, hands)))
+
+		return &discordgo.InteractionResponseData{
+			Content:         strings.Join(lines, "\n"),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Emoji:    &discordgo.ComponentEmoji{Name: "✋"},
+							Label:    "Raise/Lower Hand",
+							CustomID: nominationButtonID(buttonVote, state.Game, number),
+							Style:    discordgo.PrimaryButton,
+						},
+						discordgo.Button{
+							Emoji:    &discordgo.ComponentEmoji{Name: "🧮"},
+							Label:    "Count Votes",
+							CustomID: nominationButtonID(buttonCountVotes, state.Game, number),
+							Style:    discordgo.SuccessButton,
+						},
+						discordgo.Button{
+							Label:    "Cancel",
+							CustomID: nominationButtonID(buttonCancelNomination, state.Game, number),
+							Style:    discordgo.SecondaryButton,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	lines = append(lines, fmt.Sprintf("Votes in clockwise order: %s", renderVoters(state, n.Voters)))
+	lines = append(lines, fmt.Sprintf("**%d vote(s), %d needed.**", len(n.Voters), n.Threshold))
+	switch block, votes := state.onTheBlock(n.Day); {
+	case block != "":
+		lines = append(lines, fmt.Sprintf("<@%s> is about to be executed with %d vote(s).", block, votes))
+	case votes > 0:
+		lines = append(lines, fmt.Sprintf("The vote is tied at %d, nobody is about to be executed.", votes))
+	default:
+		lines = append(lines, "Nobody is about to be executed.")
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content:         strings.Join(lines, "\n"),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+		Components:      []discordgo.MessageComponent{},
+	}
+}
+
+// renderVoters renders the voters, marking ghost votes of dead players.
+func renderVoters(state *GameState, voters []string) string {
+	if len(voters) == 0 {
+		return "none"
+	}
+	var parts []string
+	for _, voter := range voters {
+		if state.isAlive(voter) {
+			parts = append(parts, fmt.Sprintf("<@%s>", voter))
+		} else {
+			parts = append(parts, fmt.Sprintf("<@%s> 👻", voter))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
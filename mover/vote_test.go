@@ -0,0 +1,244 @@
+package mover
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVoteThreshold(t *testing.T) {
+	for _, tc := range []struct {
+		players int
+		dead    int
+		want    int
+	}{
+		{5, 0, 3},
+		{6, 0, 3},
+		{6, 1, 3},
+		{6, 2, 2},
+		{1, 0, 1},
+	} {
+		state := &GameState{}
+		for n := 0; n < tc.players; n++ {
+			player := string(rune('a' + n))
+			state.Players = append(state.Players, player)
+			if n < tc.dead {
+				state.Dead = append(state.Dead, player)
+			}
+		}
+		if got := state.voteThreshold(); got != tc.want {
+			t.Errorf("voteThreshold() with %d players, %d dead = %d, want %d", tc.players, tc.dead, got, tc.want)
+		}
+	}
+}
+
+func TestCountVotes(t *testing.T) {
+	state := &GameState{
+		Players:        []string{"p1", "p2", "p3", "p4", "p5"},
+		Dead:           []string{"p3", "p4"},
+		GhostVotesUsed: []string{"p4"},
+	}
+	n := &Nomination{Nominator: "p1", Nominee: "p2", Hands: []string{"p1", "p5", "p4", "p3"}}
+
+	state.countVotes(n)
+
+	// Votes are counted clockwise starting after the nominee, p4 has no vote left.
+	if diff := cmp.Diff([]string{"p3", "p5", "p1"}, n.Voters); diff != "" {
+		t.Errorf("Voters mismatch (-want, +got):%s\n", diff)
+	}
+	if n.Threshold != 2 || !n.Counted {
+		t.Errorf("Expected counted vote with threshold 2, got %+v", n)
+	}
+	if diff := cmp.Diff([]string{"p4", "p3"}, state.GhostVotesUsed); diff != "" {
+		t.Errorf("Used ghost votes mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestVoudonVotes(t *testing.T) {
+	state := &GameState{
+		Players:        []string{"p1", "p2", "p3", "p4", "p5"},
+		Dead:           []string{"p3", "p4"},
+		GhostVotesUsed: []string{"p4"},
+		Grimoire:       map[string]*GrimoireEntry{"p5": {Character: voudon}},
+	}
+	n := &Nomination{Nominator: "p1", Nominee: "p2", Hands: []string{"p1", "p5", "p4", "p3"}}
+
+	state.countVotes(n)
+
+	// Only the Voudon and the dead vote, the dead keep their ghost votes.
+	if diff := cmp.Diff([]string{"p3", "p4", "p5"}, n.Voters); diff != "" {
+		t.Errorf("Voters mismatch (-want, +got):%s\n", diff)
+	}
+	if n.Threshold != 1 {
+		t.Errorf("Expected no majority to be needed, got threshold %d", n.Threshold)
+	}
+	if diff := cmp.Diff([]string{"p4"}, state.GhostVotesUsed); diff != "" {
+		t.Errorf("Used ghost votes mismatch (-want, +got):%s\n", diff)
+	}
+
+	// Once the Voudon dies, the usual rules apply again.
+	state.Dead = append(state.Dead, "p5")
+	if !state.hasVote("p1") {
+		t.Error("Expected living players to vote again.")
+	}
+	if state.hasVote("p4") {
+		t.Error("Expected p4 to have used their ghost vote.")
+	}
+}
+
+func TestOnTheBlock(t *testing.T) {
+	counted := func(day int, nominee string, votes, threshold int) *Nomination {
+		return &Nomination{Day: day, Nominee: nominee, Voters: make([]string, votes), Threshold: threshold, Counted: true}
+	}
+	for _, tc := range []struct {
+		desc        string
+		nominations []*Nomination
+		want        string
+		wantVotes   int
+	}{
+		{"no nominations", nil, "", 0},
+		{"below threshold", []*Nomination{counted(1, "p1", 2, 3)}, "", 0},
+		{"highest vote", []*Nomination{counted(1, "p1", 3, 3), counted(1, "p2", 4, 3)}, "p2", 4},
+		{"tie", []*Nomination{counted(1, "p1", 4, 3), counted(1, "p2", 4, 3)}, "", 4},
+		{"other day", []*Nomination{counted(0, "p1", 4, 3)}, "", 0},
+		{"open vote", []*Nomination{{Day: 1, Nominee: "p1", Hands: []string{"a", "b", "c"}}}, "", 0},
+	} {
+		state := &GameState{Nominations: tc.nominations}
+		got, votes := state.onTheBlock(1)
+		if got != tc.want || votes != tc.wantVotes {
+			t.Errorf("%s: onTheBlock() = %q, %d, want %q, %d", tc.desc, got, votes, tc.want, tc.wantVotes)
+		}
+	}
+}
+
+func nominateInteraction(nominator, nominee string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: slashCommandNominate,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: "nominator", Type: discordgo.ApplicationCommandOptionUser, Value: nominator},
+					{Name: "nominee", Type: discordgo.ApplicationCommandOptionUser, Value: nominee},
+				},
+			},
+		},
+	}
+}
+
+func nominationButtonInteraction(user, customID string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionMessageComponent,
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: user}},
+			Data:    discordgo.MessageComponentInteractionData{CustomID: customID},
+		},
+	}
+}
+
+func TestNominationVote(t *testing.T) {
+	b, _ := newWhisperTestBot()
+	d := &fakeDiscordSession{id: "guild"}
+	ctx := context.Background()
+
+	if err := b.store.Update("guild", func(state *GameState) error {
+		state.Running = true
+		state.Game = 42
+		state.Phase = phaseDay
+		state.Day = 1
+		state.Players = []string{"user1", "user2", "user3"}
+		state.Dead = []string{"user3"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Cannot update game state: %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc      string
+		nominator string
+		nominee   string
+	}{
+		{"nominator not seated", "storyteller", "user2"},
+		{"nominee not seated", "user1", "storyteller"},
+		{"dead nominator", "user3", "user2"},
+	} {
+		if err := b.nominate(ctx, d, nominateInteraction(tc.nominator, tc.nominee)); err == nil {
+			t.Errorf("%s: Expected error, got nil", tc.desc)
+		}
+	}
+
+	if err := b.nominate(ctx, d, nominateInteraction("user1", "user2")); err != nil {
+		t.Fatalf("Cannot nominate: %v", err)
+	}
+	if got := d.responses[0].Data.Content; !strings.Contains(got, "<@user1> nominates <@user2>") || !strings.Contains(got, "1 vote(s) needed") {
+		t.Errorf("Unexpected vote message %q", got)
+	}
+	if err := b.nominate(ctx, d, nominateInteraction("user2", "user1")); err == nil {
+		t.Error("Expected error while the vote is open, got nil")
+	}
+
+	vote := nominationButtonID(buttonVote, 42, 1)
+	button, game, number, ok := parseNominationButton(vote)
+	if !ok || button != buttonVote || game != 42 || number != 1 {
+		t.Fatalf("parseNominationButton(%q) = %q, %d, %d, %t", vote, button, game, number, ok)
+	}
+	if got := d.responses[0].Data.Components[0].(discordgo.ActionsRow).Components[0].(discordgo.Button).CustomID; got != vote {
+		t.Errorf("Unexpected custom ID %q of the vote button, want %q", got, vote)
+	}
+	// Buttons of the same nomination of a previous game are rejected.
+	if err := b.onNominationButton(ctx, d, nominationButtonInteraction("user1", nominationButtonID(buttonVote, 41, 1)), buttonVote, 41, 1); err == nil {
+		t.Error("Expected error for vote of a previous game, got nil")
+	}
+	for _, user := range []string{"user3", "user1", "user2", "user2"} {
+		if err := b.onNominationButton(ctx, d, nominationButtonInteraction(user, vote), button, game, number); err != nil {
+			t.Fatalf("Cannot vote as %s: %v", user, err)
+		}
+	}
+	if err := b.onNominationButton(ctx, d, nominationButtonInteraction("storyteller", vote), buttonVote, 42, 1); err == nil {
+		t.Error("Expected error for vote of unseated user, got nil")
+	}
+
+	if err := b.onNominationButton(ctx, d, nominationButtonInteraction("storyteller", nominationButtonID(buttonCountVotes, 42, 1)), buttonCountVotes, 42, 1); err != nil {
+		t.Fatalf("Cannot count votes: %v", err)
+	}
+	got := d.responses[len(d.responses)-1]
+	if got.Type != discordgo.InteractionResponseUpdateMessage || len(got.Data.Components) != 0 {
+		t.Errorf("Expected vote message without buttons, got %+v", got)
+	}
+	for _, want := range []string{"Votes in clockwise order: <@user3> 👻, <@user1>", "<@user2> is about to be executed with 2 vote(s)."} {
+		if !strings.Contains(got.Data.Content, want) {
+			t.Errorf("Expected vote result %q to contain %q", got.Data.Content, want)
+		}
+	}
+
+	// The ghost vote has been used.
+	if err := b.onNominationButton(ctx, d, nominationButtonInteraction("storyteller", vote), buttonVote, 42, 1); err == nil {
+		t.Error("Expected error for vote on counted nomination, got nil")
+	}
+	if err := b.nominate(ctx, d, nominateInteraction("user2", "user1")); err != nil {
+		t.Fatalf("Cannot nominate: %v", err)
+	}
+	if err := b.onNominationButton(ctx, d, nominationButtonInteraction("user3", nominationButtonID(buttonVote, 42, 2)), buttonVote, 42, 2); err == nil {
+		t.Error("Expected error for second ghost vote, got nil")
+	}
+	if err := b.nominate(ctx, d, nominateInteraction("user1", "user3")); err == nil {
+		t.Error("Expected error for second nomination by user1, got nil")
+	}
+
+	if err := b.onNominationButton(ctx, d, nominationButtonInteraction("storyteller", nominationButtonID(buttonCancelNomination, 42, 2)), buttonCancelNomination, 42, 2); err != nil {
+		t.Fatalf("Cannot cancel nomination: %v", err)
+	}
+	state, err := b.store.Get("guild")
+	if err != nil {
+		t.Fatalf("Cannot load game state: %v", err)
+	}
+	if len(state.Nominations) != 1 {
+		t.Errorf("Expected the cancelled nomination to be removed, got %d nominations", len(state.Nominations))
+	}
+}
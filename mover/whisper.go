@@ -0,0 +1,404 @@
+package mover
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// Subcommands of the /whispers slash command.
+const (
+	whispersCommandList = "list"
+	whispersCommandEnd  = "end"
+	whispersCommandLog  = "log"
+)
+
+// whisperPlayerOptions is the number of player options of the /whisper slash command.
+const whisperPlayerOptions = 4
+
+// whisperChannelName is the name of voice channels created for whispers if there is no free voice
+// channel in the day category.
+const whisperChannelName = "Whisper"
+
+// whisperEndRetryDelay is the delay before ending a whisper is retried if the guild is busy with
+// another movement.
+const whisperEndRetryDelay = 5 * time.Second
+
+// maxWhisperEndRetries is the number of times ending an expired whisper is retried after an error.
+const maxWhisperEndRetries = 3
+
+// whisper is a private conversation of some players in a day phase voice channel.
+type whisper struct {
+	guild        string
+	channel      string
+	participants []string
+	started      time.Time
+	// deadline is zero if the whisper has no time limit.
+	deadline time.Time
+	// created is true iff the channel was created for this whisper and is deleted afterwards.
+	created bool
+	timer   *time.Timer
+	// endRetries is the number of failed attempts to end the whisper after it expired.
+	endRetries int
+}
+
+// stop stops the whisper's timer, if any.
+func (w *whisper) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// whisperRegistry keeps track of all active whispers.
+type whisperRegistry struct {
+	mu sync.Mutex
+	// whispers maps guild IDs to channel IDs to active whispers.
+	whispers map[string]map[string]*whisper
+}
+
+// newWhisperRegistry creates a new registry without active whispers.
+func newWhisperRegistry() *whisperRegistry {
+	return &whisperRegistry{whispers: make(map[string]map[string]*whisper)}
+}
+
+// add registers a new active whisper.
+func (r *whisperRegistry) add(w *whisper) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.whispers[w.guild] == nil {
+		r.whispers[w.guild] = make(map[string]*whisper)
+	}
+	r.whispers[w.guild][w.channel] = w
+}
+
+// get returns the active whisper in the channel, or nil if there is none.
+func (r *whisperRegistry) get(guild, channel string) *whisper {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.whispers[guild][channel]
+}
+
+// remove forgets the active whisper in the channel and returns it, or nil if there is none.
+func (r *whisperRegistry) remove(guild, channel string) *whisper {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w := r.whispers[guild][channel]
+	delete(r.whispers[guild], channel)
+	return w
+}
+
+// removeAll forgets all active whispers of the guild and returns them.
+func (r *whisperRegistry) removeAll(guild string) []*whisper {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var whispers []*whisper
+	for _, w := range r.whispers[guild] {
+		whispers = append(whispers, w)
+	}
+	delete(r.whispers, guild)
+	return whispers
+}
+
+// list returns all active whispers of the guild, oldest first.
+func (r *whisperRegistry) list(guild string) []*whisper {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var whispers []*whisper
+	for _, w := range r.whispers[guild] {
+		whispers = append(whispers, w)
+	}
+	sort.Slice(whispers, func(i, j int) bool {
+		if !whispers[i].started.Equal(whispers[j].started) {
+			return whispers[i].started.Before(whispers[j].started)
+		}
+		return whispers[i].channel < whispers[j].channel
+	})
+	return whispers
+}
+
+// startWhisper handles the /whisper slash command. The invoking player and all mentioned players
+// are moved from Town Square into a free voice channel of the day category. A new voice channel is
+// created if there is no free one. Story tellers can arrange whispers without taking part.
+func (b *Bot) startWhisper(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	vs, err := b.buildDiscordVoiceState(ctx, s, i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot build voice state: %w", err)
+	}
+
+	state, err := b.store.Get(i.GuildID)
+	if err != nil {
+		return fmt.Errorf("cannot load game state: %w", err)
+	}
+	if state.Phase == phaseNight {
+		return fmt.Errorf("whispers are only possible during the day")
+	}
+
+	var participants []string
+	if !vs.isStoryTeller(i.Member) {
+		participants = append(participants, i.Member.User.ID)
+	}
+	for _, option := range i.ApplicationCommandData().Options {
+		if user := option.UserValue(nil); !slices.Contains(participants, user.ID) {
+			participants = append(participants, user.ID)
+		}
+	}
+	if len(participants) < 2 {
+		return fmt.Errorf("a whisper needs at least 2 players")
+	}
+	for _, user := range participants {
+		if !state.isSeated(user) {
+			return fmt.Errorf("<@%s> is not seated in the game", user)
+		}
+		if userVoiceState := vs.userToVoiceState[user]; userVoiceState == nil || userVoiceState.ChannelID != vs.townSquare.ID {
+			return fmt.Errorf("<@%s> is not in Town Square", user)
+		}
+	}
+	if err := checkWhisperLimits(b.cfg.ForGuild(i.GuildID), state, participants); err != nil {
+		return err
+	}
+
+	channel, created, err := b.findWhisperChannel(ctx, s, i.GuildID, vs)
+	if err != nil {
+		return err
+	}
+
+	plan := &movementPlan{moves: make(map[string]string), guild: i.GuildID}
+	for _, user := range participants {
+		plan.moves[user] = channel.ID
+	}
+	ok, err := b.dispatchPlan(ctx, s, i, plan)
+	if !ok {
+		if created {
+			b.deleteWhisperChannel(s, i.GuildID, channel.ID)
+		}
+		return err
+	}
+
+	log.Printf("Started whisper of %v in channel %s of guild %s.", participants, channel.ID, i.GuildID)
+	w := &whisper{
+		guild:        i.GuildID,
+		channel:      channel.ID,
+		participants: participants,
+		started:      time.Now(),
+		created:      created,
+	}
+	if seconds := b.cfg.ForGuild(i.GuildID).WhisperSeconds; seconds > 0 {
+		limit := time.Duration(seconds) * time.Second
+		w.deadline = w.started.Add(limit)
+		w.timer = time.AfterFunc(limit, func() { b.expireWhisper(s, w) })
+	}
+	b.whispers.add(w)
+
+	return nil
+}
+
+// findWhisperChannel returns an empty voice channel of the day category that is not used by
+// another whisper. Creates a new voice channel if there is none. Returns true iff the channel was
+// created.
+func (b *Bot) findWhisperChannel(ctx context.Context, s discordSession, guildID string, vs *discordVoiceState) (*discordgo.Channel, bool, error) {
+	occupied := make(map[string]bool)
+	for _, userVoiceState := range vs.userToVoiceState {
+		occupied[userVoiceState.ChannelID] = true
+	}
+
+	var candidates []*discordgo.Channel
+	for _, channel := range vs.channels {
+		if channel.Type != discordgo.ChannelTypeGuildVoice || channel.ParentID != vs.dayCategory.ID || channel.ID == vs.townSquare.ID {
+			continue
+		}
+		if occupied[channel.ID] || vs.ignoredChannelIDs[channel.ID] || b.whispers.get(guildID, channel.ID) != nil {
+			continue
+		}
+		candidates = append(candidates, channel)
+	}
+	if len(candidates) > 0 {
+		slices.SortFunc(candidates, func(a, b *discordgo.Channel) int {
+			if a.Position != b.Position {
+				return a.Position - b.Position
+			}
+			return strings.Compare(a.ID, b.ID)
+		})
+		return candidates[0], false, nil
+	}
+
+	channel, err := s.GuildChannelCreateComplex(guildID, discordgo.GuildChannelCreateData{
+		Name:     whisperChannelName,
+		Type:     discordgo.ChannelTypeGuildVoice,
+		ParentID: vs.dayCategory.ID,
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot create whisper channel: %w", err)
+	}
+	return channel, true, nil
+}
+
+// endWhispers ends the whispers in the channels and returns everyone in the channels to Town
+// Square with a single movement. Returns false if the movement could not be dispatched because the
+// guild is busy.
+func (b *Bot) endWhispers(ctx context.Context, s discordSession, guildID string, channelIDs []string) (bool, error) {
+	var whispers []*whisper
+	for _, channelID := range channelIDs {
+		w := b.whispers.get(guildID, channelID)
+		if w == nil {
+			return false, fmt.Errorf("there is no active whisper in <#%s>", channelID)
+		}
+		whispers = append(whispers, w)
+	}
+
+	vs, err := b.buildDiscordVoiceState(ctx, s, guildID)
+	if err != nil {
+		return false, fmt.Errorf("cannot build voice state: %w", err)
+	}
+
+	plan := &movementPlan{moves: make(map[string]string), guild: guildID}
+	for user, userVoiceState := range vs.userToVoiceState {
+		if slices.Contains(channelIDs, userVoiceState.ChannelID) {
+			plan.moves[user] = vs.townSquare.ID
+		}
+	}
+	plan.onDone = func(*movementReport) {
+		for _, w := range whispers {
+			if w.created {
+				b.deleteWhisperChannel(s, guildID, w.channel)
+			}
+		}
+	}
+	if !b.plans.Dispatch(plan) {
+		return false, nil
+	}
+
+	for _, w := range whispers {
+		b.whispers.remove(guildID, w.channel)
+		w.stop()
+		log.Printf("Ended whisper in channel %s of guild %s.", w.channel, guildID)
+	}
+
+	return true, nil
+}
+
+// expireWhisper ends the whisper once its time limit has passed. Retries later if the guild is
+// busy with another movement or ending the whisper failed. Gives up on the whisper after
+// maxWhisperEndRetries failures.
+func (b *Bot) expireWhisper(s discordSession, w *whisper) {
+	if b.whispers.get(w.guild, w.channel) != w {
+		return // Whisper has already ended.
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(b.cfg.ForGuild(w.guild).PerRequestSeconds)*time.Second)
+	defer cancel()
+
+	ok, err := b.endWhispers(ctx, s, w.guild, []string{w.channel})
+	if err != nil {
+		log.Printf("Cannot end whisper in channel %s of guild %s: %v", w.channel, w.guild, err)
+		w.endRetries++
+		if w.endRetries > maxWhisperEndRetries {
+			log.Printf("Giving up on whisper in channel %s of guild %s.", w.channel, w.guild)
+			b.whispers.remove(w.guild, w.channel)
+			return
+		}
+	}
+	if !ok {
+		w.timer.Reset(whisperEndRetryDelay)
+	}
+}
+
+// closeWhispers forgets all active whispers of the guild and deletes all channels that were
+// created for them. Called after a phase transition moved everyone out of the whisper channels.
+func (b *Bot) closeWhispers(s discordSession, guildID string) {
+	for _, w := range b.whispers.removeAll(guildID) {
+		w.stop()
+		if w.created {
+			b.deleteWhisperChannel(s, guildID, w.channel)
+		}
+	}
+}
+
+// deleteWhisperChannel deletes a voice channel that was created for a whisper.
+func (b *Bot) deleteWhisperChannel(s discordSession, guildID, channelID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(b.cfg.ForGuild(guildID).PerRequestSeconds)*time.Second)
+	defer cancel()
+
+	if _, err := s.ChannelDelete(channelID, discordgo.WithContext(ctx)); err != nil {
+		log.Printf("Cannot delete whisper channel %s of guild %s: %v", channelID, guildID, err)
+	}
+}
+
+// onWhispersCommand handles the /whispers slash command group.
+func (b *Bot) onWhispersCommand(ctx context.Context, s discordSession, i *discordgo.InteractionCreate) error {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return fmt.Errorf("missing /whispers subcommand")
+	}
+
+	switch options[0].Name {
+	case whispersCommandList:
+		return respondEphemeral(ctx, s, i, renderWhispers(b.whispers.list(i.GuildID), time.Now()))
+	case whispersCommandEnd:
+		var channels []string
+		if len(options[0].Options) > 0 {
+			channels = append(channels, options[0].Options[0].Value.(string))
+		} else {
+			for _, w := range b.whispers.list(i.GuildID) {
+				channels = append(channels, w.channel)
+			}
+		}
+		if len(channels) == 0 {
+			return fmt.Errorf("there are no active whispers")
+		}
+
+		ok, err := b.endWhispers(ctx, s, i.GuildID, channels)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return respondEphemeral(ctx, s, i, "Existing player movement has not finished yet, please wait.")
+		}
+		var lines []string
+		for _, channel := range channels {
+			lines = append(lines, fmt.Sprintf("Ended whisper in <#%s>.", channel))
+		}
+		return respondEphemeral(ctx, s, i, strings.Join(lines, "\n"))
+	case whispersCommandLog:
+		state, err := b.store.Get(i.GuildID)
+		if err != nil {
+			return fmt.Errorf("cannot load game state: %w", err)
+		}
+		day := state.Day
+		if len(options[0].Options) > 0 {
+			day = int(options[0].Options[0].IntValue())
+		}
+		return respondEphemeral(ctx, s, i, renderWhisperLog(state, day, time.Now()))
+	}
+
+	return fmt.Errorf("unknown /whispers subcommand: %s", options[0].Name)
+}
+
+// renderWhispers renders a short human readable list of all active whispers.
+func renderWhispers(whispers []*whisper, now time.Time) string {
+	if len(whispers) == 0 {
+		return "There are no active whispers."
+	}
+
+	lines := []string{fmt.Sprintf("**Active whispers (%d):**", len(whispers))}
+	for _, w := range whispers {
+		line := fmt.Sprintf("<#%s>: %s, for %s", w.channel, mentions(w.participants), now.Sub(w.started).Round(time.Second))
+		if !w.deadline.IsZero() {
+			line += fmt.Sprintf(", %s left", w.deadline.Sub(now).Round(time.Second))
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
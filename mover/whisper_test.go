@@ -0,0 +1,291 @@
+package mover
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-cmp/cmp"
+)
+
+// whisperInteraction returns a /whisper interaction of the member with all given players.
+func whisperInteraction(member *discordgo.Member, players ...string) *discordgo.InteractionCreate {
+	data := discordgo.ApplicationCommandInteractionData{Name: slashCommandWhisper}
+	for _, player := range players {
+		data.Options = append(data.Options, &discordgo.ApplicationCommandInteractionDataOption{
+			Type:  discordgo.ApplicationCommandOptionUser,
+			Value: player,
+		})
+	}
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild",
+			Member:  member,
+			Data:    data,
+		},
+	}
+}
+
+func newWhisperTestBot() (*Bot, chan *movementPlan) {
+	return newTestBot(&Config{
+		Tokens:                  []string{"a", "b", "c"},
+		NightPhaseCategory:      "night phase",
+		DayPhaseCategory:        "day phase",
+		TownSquare:              "townsquare",
+		StoryTellerRole:         "storyteller",
+		MovementDeadlineSeconds: 15,
+		PerRequestSeconds:       5,
+		MaxConcurrentRequests:   3,
+	})
+}
+
+var (
+	whisperPlayer      = &discordgo.Member{User: &discordgo.User{ID: "user1"}, Roles: []string{"role1"}}
+	whisperStoryTeller = &discordgo.Member{User: &discordgo.User{ID: "storyteller"}, Roles: []string{"storyteller"}}
+)
+
+func TestWhisper(t *testing.T) {
+	b, plans := newWhisperTestBot()
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user1", ChannelID: "townsquare"},
+			{UserID: "user2", ChannelID: "townsquare"},
+			{UserID: "user3", ChannelID: "barber"},
+			{UserID: "storyteller", ChannelID: "inn"},
+		},
+	}
+	ctx := context.Background()
+
+	// The invoking player whispers with the mentioned player in the only free channel.
+	if err := b.startWhisper(ctx, d, whisperInteraction(whisperPlayer, "user2")); err != nil {
+		t.Fatalf("Cannot start whisper: %v", err)
+	}
+	select {
+	case plan := <-plans:
+		if diff := cmp.Diff(map[string]string{"user1": "hotel", "user2": "hotel"}, plan.moves); diff != "" {
+			t.Errorf("Whisper moves mismatch (-want, +got):%s\n", diff)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	got := renderWhispers(b.whispers.list("guild"), time.Now())
+	if want := "<#hotel>: <@user1>, <@user2>"; !strings.Contains(got, want) {
+		t.Errorf("Expected whisper list %q to contain %q", got, want)
+	}
+
+	// Ending the whisper returns everyone in the whisper channel to Town Square.
+	d.voiceStates = []*discordgo.VoiceState{
+		{UserID: "user1", ChannelID: "hotel"},
+		{UserID: "user2", ChannelID: "hotel"},
+		{UserID: "user3", ChannelID: "barber"},
+	}
+	if ok, err := b.endWhispers(ctx, d, "guild", []string{"hotel"}); !ok || err != nil {
+		t.Fatalf("Cannot end whisper: %t, %v", ok, err)
+	}
+	select {
+	case plan := <-plans:
+		if diff := cmp.Diff(map[string]string{"user1": "townsquare", "user2": "townsquare"}, plan.moves); diff != "" {
+			t.Errorf("End of whisper moves mismatch (-want, +got):%s\n", diff)
+		}
+		plan.onDone(&movementReport{})
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	if w := b.whispers.list("guild"); len(w) != 0 {
+		t.Errorf("Expected no active whispers, got %d", len(w))
+	}
+	if len(d.deletedChannels) != 0 {
+		t.Errorf("Expected existing whisper channel to be kept, got deleted channels %v", d.deletedChannels)
+	}
+}
+
+func TestWhisperCreatesChannel(t *testing.T) {
+	b, plans := newWhisperTestBot()
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user1", ChannelID: "townsquare"},
+			{UserID: "user2", ChannelID: "townsquare"},
+			{UserID: "user3", ChannelID: "barber"},
+			{UserID: "storyteller", ChannelID: "inn"},
+			{UserID: "storyteller2", ChannelID: "hotel"},
+		},
+	}
+	ctx := context.Background()
+
+	// Story tellers can arrange whispers without taking part.
+	if err := b.startWhisper(ctx, d, whisperInteraction(whisperStoryTeller, "user1", "user2")); err != nil {
+		t.Fatalf("Cannot start whisper: %v", err)
+	}
+	want := []discordgo.GuildChannelCreateData{{Name: whisperChannelName, Type: discordgo.ChannelTypeGuildVoice, ParentID: "day phase"}}
+	if diff := cmp.Diff(want, d.createdChannels); diff != "" {
+		t.Errorf("Created channels mismatch (-want, +got):%s\n", diff)
+	}
+	select {
+	case plan := <-plans:
+		if diff := cmp.Diff(map[string]string{"user1": "created1", "user2": "created1"}, plan.moves); diff != "" {
+			t.Errorf("Whisper moves mismatch (-want, +got):%s\n", diff)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	// The day phase ends all whispers and deletes their channels once everyone has moved.
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "guild",
+			Member:  whisperStoryTeller,
+		},
+	}
+	if err := b.prepareDayMoves(ctx, d, i); err != nil {
+		t.Fatalf("Cannot prepare day moves: %v", err)
+	}
+	select {
+	case plan := <-plans:
+		plan.onDone(&movementReport{})
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	if w := b.whispers.list("guild"); len(w) != 0 {
+		t.Errorf("Expected no active whispers, got %d", len(w))
+	}
+	if diff := cmp.Diff([]string{"created1"}, d.deletedChannels); diff != "" {
+		t.Errorf("Deleted channels mismatch (-want, +got):%s\n", diff)
+	}
+}
+
+func TestWhisperErrors(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		phase   string
+		member  *discordgo.Member
+		players []string
+	}{
+		{
+			desc:    "not enough players",
+			member:  whisperStoryTeller,
+			players: []string{"user1"},
+		},
+		{
+			desc:    "player outside of Town Square",
+			member:  whisperPlayer,
+			players: []string{"user3"},
+		},
+		{
+			desc:    "night phase",
+			phase:   phaseNight,
+			member:  whisperPlayer,
+			players: []string{"user2"},
+		},
+	} {
+		b, plans := newWhisperTestBot()
+		d := &fakeDiscordSession{
+			id: "guild",
+			voiceStates: []*discordgo.VoiceState{
+				{UserID: "user1", ChannelID: "townsquare"},
+				{UserID: "user2", ChannelID: "townsquare"},
+				{UserID: "user3", ChannelID: "barber"},
+			},
+		}
+		if err := b.store.Update("guild", func(state *GameState) error {
+			state.Phase = tc.phase
+			return nil
+		}); err != nil {
+			t.Fatalf("Cannot update game state: %v", err)
+		}
+
+		if err := b.startWhisper(context.Background(), d, whisperInteraction(tc.member, tc.players...)); err == nil {
+			t.Errorf("%s: Expected error, got nil", tc.desc)
+		}
+		select {
+		case plan := <-plans:
+			t.Errorf("%s: Expected no plan, got %v", tc.desc, plan)
+		default:
+		}
+	}
+}
+
+func TestEndAllWhispers(t *testing.T) {
+	b, plans := newWhisperTestBot()
+	d := &fakeDiscordSession{
+		id: "guild",
+		voiceStates: []*discordgo.VoiceState{
+			{UserID: "user1", ChannelID: "hotel"},
+			{UserID: "user2", ChannelID: "hotel"},
+			{UserID: "user3", ChannelID: "barber"},
+			{UserID: "storyteller", ChannelID: "inn"},
+		},
+	}
+	b.whispers.add(&whisper{guild: "guild", channel: "hotel", participants: []string{"user1", "user2"}})
+	b.whispers.add(&whisper{guild: "guild", channel: "barber", participants: []string{"user3"}})
+
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild",
+			Member:  whisperStoryTeller,
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: slashCommandWhispers,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: whispersCommandEnd, Type: discordgo.ApplicationCommandOptionSubCommand},
+				},
+			},
+		},
+	}
+	if err := b.onWhispersCommand(context.Background(), d, i); err != nil {
+		t.Fatalf("Cannot end whispers: %v", err)
+	}
+
+	// All whispers end with a single movement.
+	select {
+	case plan := <-plans:
+		want := map[string]string{"user1": "townsquare", "user2": "townsquare", "user3": "townsquare"}
+		if diff := cmp.Diff(want, plan.moves); diff != "" {
+			t.Errorf("End of whispers moves mismatch (-want, +got):%s\n", diff)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected to receive plan, got nothing.")
+	}
+	b.plans.Wait()
+
+	if w := b.whispers.list("guild"); len(w) != 0 {
+		t.Errorf("Expected no active whispers, got %d", len(w))
+	}
+	got := d.responses[len(d.responses)-1].Data.Content
+	for _, want := range []string{"Ended whisper in <#hotel>.", "Ended whisper in <#barber>."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected response %q to contain %q", got, want)
+		}
+	}
+}
+
+func TestExpireWhisperGivesUp(t *testing.T) {
+	b, _ := newWhisperTestBot()
+	// The voice state of an unknown guild cannot be built, so the whisper never ends.
+	d := &fakeDiscordSession{id: "other"}
+	w := &whisper{guild: "guild", channel: "hotel", timer: time.AfterFunc(time.Hour, func() {})}
+	defer w.stop()
+	b.whispers.add(w)
+
+	for n := 0; n < maxWhisperEndRetries; n++ {
+		b.expireWhisper(d, w)
+		if b.whispers.get("guild", "hotel") != w {
+			t.Fatalf("Expected whisper to be retried after %d failures", n+1)
+		}
+	}
+	b.expireWhisper(d, w)
+	if b.whispers.get("guild", "hotel") != nil {
+		t.Error("Expected whisper to be given up after too many failures.")
+	}
+}